@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+const baselineVersion = 1
+
+// baseline is a record of the problems which were found once.
+// Problems are identified with their fingerprints, so it survives changes of line numbers.
+type baseline struct {
+	Version  int               `json:"version"`
+	Findings []baselineFinding `json:"findings"`
+
+	remains map[string]int  // fingerprint => the number of the findings not found yet
+	linted  map[string]bool // filenames which are linted
+}
+
+type baselineFinding struct {
+	Fingerprint string `json:"fingerprint"`
+	Filename    string `json:"filename"`
+	Function    string `json:"function,omitempty"`
+	Variable    string `json:"variable,omitempty"`
+	Category    string `json:"category,omitempty"`
+	Text        string `json:"text"`
+	Count       int    `json:"count"`
+}
+
+func readBaseline(filename string) (*baseline, error) {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var b baseline
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return nil, fmt.Errorf("invalid baseline %s: %v", filename, err)
+	}
+	if b.Version != baselineVersion {
+		return nil, fmt.Errorf("unsupported baseline version %d in %s", b.Version, filename)
+	}
+	b.remains = map[string]int{}
+	b.linted = map[string]bool{}
+	for i, f := range b.Findings {
+		b.Findings[i].Filename = path.Clean(f.Filename)
+		b.remains[f.Fingerprint] += f.Count
+	}
+	return &b, nil
+}
+
+// filter removes problems recorded in the baseline.
+func (b *baseline) filter(filenames []string, problems []scopelint.Problem) []scopelint.Problem {
+	for _, filename := range filenames {
+		b.linted[baselineFilename(filename)] = true
+	}
+	var filtered []scopelint.Problem
+	for _, p := range problems {
+		if !p.Ignored {
+			if fp := p.Fingerprint(); b.remains[fp] > 0 {
+				b.remains[fp]--
+				continue
+			}
+		}
+		filtered = append(filtered, p)
+	}
+	return filtered
+}
+
+// resolved returns findings in the baseline which are not found any more.
+// Findings in files which were not linted are not resolved, unless the files are removed.
+func (b *baseline) resolved() []baselineFinding {
+	var resolved []baselineFinding
+	for _, f := range b.Findings {
+		count := b.remains[f.Fingerprint]
+		if count == 0 {
+			continue
+		}
+		if _, err := os.Stat(filepath.FromSlash(f.Filename)); !b.linted[f.Filename] && err == nil {
+			continue
+		}
+		if count > f.Count {
+			count = f.Count
+		}
+		b.remains[f.Fingerprint] -= count
+		f.Count = count
+		resolved = append(resolved, f)
+	}
+	return resolved
+}
+
+// baselineFilename returns the filename in baselines, which is relative to the working directory if it is in it.
+func baselineFilename(filename string) string {
+	rel, _ := relativePath(filename)
+	return rel
+}
+
+// baselineWriter records active problems to write a new baseline.
+type baselineWriter struct {
+	findings map[string]*baselineFinding
+}
+
+func (w *baselineWriter) record(problems []scopelint.Problem) {
+	if w.findings == nil {
+		w.findings = map[string]*baselineFinding{}
+	}
+	for _, p := range problems {
+		if p.Ignored {
+			continue
+		}
+		fp := p.Fingerprint()
+		if f, ok := w.findings[fp]; ok {
+			f.Count++
+			continue
+		}
+		w.findings[fp] = &baselineFinding{
+			Fingerprint: fp,
+			Filename:    baselineFilename(p.Position.Filename),
+			Function:    p.Function,
+			Variable:    p.Variable,
+			Category:    p.Category,
+			Text:        p.Text,
+			Count:       1,
+		}
+	}
+}
+
+func (w *baselineWriter) writeTo(out io.Writer) error {
+	b := baseline{Version: baselineVersion, Findings: []baselineFinding{}}
+	for _, f := range w.findings {
+		b.Findings = append(b.Findings, *f)
+	}
+	sort.Slice(b.Findings, func(i, j int) bool {
+		fi, fj := b.Findings[i], b.Findings[j]
+		if fi.Filename != fj.Filename {
+			return fi.Filename < fj.Filename
+		}
+		if fi.Function != fj.Function {
+			return fi.Function < fj.Function
+		}
+		return fi.Fingerprint < fj.Fingerprint
+	})
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+func (w *baselineWriter) write(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := w.writeTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
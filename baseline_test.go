@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/token"
+	"testing"
+
+	"github.com/kyoh86/scopelint/scopelint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBaselineFilenames(t *testing.T) {
+	problem := func(filename string) scopelint.Problem {
+		return scopelint.Problem{
+			Position: token.Position{Filename: filename, Line: 3, Column: 10},
+			Category: "range-scope",
+			Variable: "v",
+			Text:     `Using the variable on range scope "v" in function literal`,
+			LineText: "\t\tprint(v)",
+		}
+	}
+
+	var w baselineWriter
+	w.record([]scopelint.Problem{problem("./x.go")})
+	var buf bytes.Buffer
+	require.NoError(t, w.writeTo(&buf))
+	var b baseline
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &b))
+	require.Len(t, b.Findings, 1)
+	assert.Equal(t, "x.go", b.Findings[0].Filename)
+
+	b.remains = map[string]int{b.Findings[0].Fingerprint: 1}
+	b.linted = map[string]bool{}
+	assert.Empty(t, b.filter([]string{"x.go"}, []scopelint.Problem{problem("x.go")}), "files given in other forms match the baseline")
+	assert.Empty(t, b.resolved())
+
+	b.remains = map[string]int{b.Findings[0].Fingerprint: 1}
+	b.linted = map[string]bool{}
+	assert.Empty(t, b.filter([]string{"./x.go"}, nil))
+	assert.Len(t, b.resolved(), 1, "findings in linted files are resolved")
+}
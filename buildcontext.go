@@ -0,0 +1,16 @@
+package main
+
+import "strings"
+
+// setupBuildContext sets the build context from the --tags, --goos and --goarch flags.
+func setupBuildContext() {
+	if params.tags != "" {
+		buildContext.BuildTags = strings.FieldsFunc(params.tags, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+	if params.goos != "" {
+		buildContext.GOOS = params.goos
+	}
+	if params.goarch != "" {
+		buildContext.GOARCH = params.goarch
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// cacheVersion is the version of the format of cache entries.
+// It will be incremented on incompatible changes, and entries of other versions are ignored.
+const cacheVersion = 1
+
+// diskCache stores problems found in packages in files under a directory.
+//
+// An entry is keyed by a hash of the files in the package, the build of scopelint (see buildID),
+// the effective configuration and the language version.
+// It is stored in `<dir>/<first 2 characters of the key>/<key>` as a line of the SHA-256 checksum of the content
+// followed by the content in JSON, so that corrupted entries are detected and ignored.
+type diskCache struct {
+	dir  string
+	warn sync.Once
+}
+
+// cacheEntry is the content of a cache file.
+type cacheEntry struct {
+	Version  int                 `json:"version"`
+	Key      string              `json:"key"`
+	Expires  *time.Time          `json:"expires,omitempty"` // the time when a directive ignoring some problems expires
+	Problems []scopelint.Problem `json:"problems"`
+}
+
+// newCache returns the cache following the --cache-dir and --no-cache flags, or nil if it is disabled.
+func newCache() scopelint.Cache {
+	if params.noCache {
+		return nil
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: cache is disabled: %v\n", err)
+		return nil
+	}
+	return &diskCache{dir: dir}
+}
+
+// cacheDir returns the directory of the cache: the one given by --cache-dir,
+// or `scopelint` in the user cache directory like `$XDG_CACHE_HOME/scopelint`.
+func cacheDir() (string, error) {
+	if params.cacheDir != "" {
+		return params.cacheDir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "scopelint"), nil
+}
+
+// key returns the key of the entry of the package, or false if it cannot be cached.
+func (c *diskCache) key(pkg *scopelint.LoadedPackage, linter *scopelint.Linter) (string, bool) {
+	cfg, err := loadConfig(pkg.Dir)
+	if err != nil {
+		return "", false
+	}
+	settings, err := json.Marshal(struct {
+		Config    configFile `json:"config"`
+		Callbacks []string   `json:"callbacks"`
+	}{cfg.configFile, linter.Callbacks})
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "scopelint cache %d\nversion %s\ngo %s\nconfig %s\n", cacheVersion, buildID(), linter.GoVersion, settings)
+	for _, filename := range pkg.Filenames() {
+		src := pkg.Files[filename]
+		fmt.Fprintf(h, "file %q %d\n", filename, len(src))
+		h.Write(src)
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+var (
+	buildIDOnce  sync.Once
+	buildIDValue string
+)
+
+// buildID identifies the build of scopelint, so that upgraded binaries do not use problems found by older ones.
+// It is the version set by the release build, the version and checksum of the main module for `go install`,
+// or a hash of the executable for development builds, whose version is always "snapshot".
+func buildID() string {
+	buildIDOnce.Do(func() {
+		buildIDValue = readBuildID()
+	})
+	return buildIDValue
+}
+
+func readBuildID() string {
+	if version != "snapshot" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return fmt.Sprintf("module %s %s %s", info.Main.Path, info.Main.Version, info.Main.Sum)
+	}
+	if exe, err := os.Executable(); err == nil {
+		if f, err := os.Open(exe); err == nil {
+			defer f.Close()
+			h := sha256.New()
+			if _, err := io.Copy(h, f); err == nil {
+				return "executable " + hex.EncodeToString(h.Sum(nil))
+			}
+		}
+	}
+	// Unknown builds never share entries with others.
+	return fmt.Sprintf("unknown %d", time.Now().UnixNano())
+}
+
+func (c *diskCache) filename(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// Get implements scopelint.Cache.
+func (c *diskCache) Get(pkg *scopelint.LoadedPackage, linter *scopelint.Linter) ([]scopelint.Problem, bool) {
+	key, ok := c.key(pkg, linter)
+	if !ok {
+		return nil, false
+	}
+	filename := c.filename(key)
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, false
+	}
+	entry, err := decodeCacheEntry(raw)
+	if err != nil || entry.Key != key {
+		// Corrupted: it will be replaced by a new one.
+		os.Remove(filename)
+		return nil, false
+	}
+	if entry.Version != cacheVersion || (entry.Expires != nil && !time.Now().Before(*entry.Expires)) {
+		return nil, false
+	}
+	return entry.Problems, true
+}
+
+// Put implements scopelint.Cache.
+// Failures in writing are warned once, and do not fail linting.
+func (c *diskCache) Put(pkg *scopelint.LoadedPackage, linter *scopelint.Linter, problems []scopelint.Problem) {
+	key, ok := c.key(pkg, linter)
+	if !ok {
+		return
+	}
+	entry := cacheEntry{Version: cacheVersion, Key: key, Problems: problems}
+	for _, p := range problems {
+		if p.Ignored && p.Directive != nil && !p.Directive.Expires.IsZero() {
+			if entry.Expires == nil || p.Directive.Expires.Before(*entry.Expires) {
+				expires := p.Directive.Expires
+				entry.Expires = &expires
+			}
+		}
+	}
+	if err := c.write(key, entry); err != nil {
+		c.warn.Do(func() {
+			fmt.Fprintf(os.Stderr, "warning: failed to write the cache: %v\n", err)
+		})
+	}
+}
+
+// write writes the entry atomically.
+func (c *diskCache) write(key string, entry cacheEntry) error {
+	raw, err := encodeCacheEntry(entry)
+	if err != nil {
+		return err
+	}
+	filename := c.filename(key)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(raw)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func encodeCacheEntry(entry cacheEntry) ([]byte, error) {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	return append([]byte(hex.EncodeToString(sum[:])+"\n"), content...), nil
+}
+
+func decodeCacheEntry(raw []byte) (*cacheEntry, error) {
+	i := bytes.IndexByte(raw, '\n')
+	if i < 0 {
+		return nil, fmt.Errorf("no checksum")
+	}
+	sum := sha256.Sum256(raw[i+1:])
+	if string(raw[:i]) != hex.EncodeToString(sum[:]) {
+		return nil, fmt.Errorf("checksum mismatch")
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(raw[i+1:], &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// cleanCache removes all entries in the cache directory.
+// Only the subdirectories which scopelint makes are removed, in case the directory is shared with others.
+func cleanCache() error {
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, fi := range infos {
+		if !fi.IsDir() || !isCacheSubdir(fi.Name()) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, fi.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isCacheSubdir reports whether the name is of a subdirectory of the cache: 2 lowercase hexadecimal digits.
+func isCacheSubdir(name string) bool {
+	if len(name) != 2 {
+		return false
+	}
+	for _, c := range name {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kyoh86/scopelint/scopelint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scopelint-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cache := &diskCache{dir: dir}
+	linter := &scopelint.Linter{GoVersion: "1.21"}
+	pkg := &scopelint.LoadedPackage{Dir: ".", Files: map[string][]byte{"p/p.go": []byte(`package p
+
+func f() (fs []func()) {
+	for _, v := range []int{1} {
+		fs = append(fs, func() { print(v) })
+		fs = append(fs, func() { print(v) }) //scopelint:ignore,expires=2999-01-01 // known
+	}
+	return
+}
+`)}}
+	problems, err := linter.LintFiles(pkg.Files)
+	require.NoError(t, err)
+	require.Len(t, problems, 2)
+
+	_, ok := cache.Get(pkg, linter)
+	assert.False(t, ok)
+	cache.Put(pkg, linter, problems)
+	cached, ok := cache.Get(pkg, linter)
+	require.True(t, ok)
+	assert.Equal(t, len(problems), len(cached))
+	assert.Equal(t, problems[0].Position, cached[0].Position)
+	assert.Equal(t, problems[1].Fingerprint(), cached[1].Fingerprint())
+
+	assert.Contains(t, buildID(), "executable ", "development builds are identified by their executables")
+	assert.Equal(t, buildID(), buildID())
+
+	_, ok = cache.Get(pkg, &scopelint.Linter{GoVersion: "1.22"})
+	assert.False(t, ok, "the language version is in the key")
+	changed := &scopelint.LoadedPackage{Dir: ".", Files: map[string][]byte{"p/p.go": []byte("package p\n")}}
+	_, ok = cache.Get(changed, linter)
+	assert.False(t, ok, "the contents of the files are in the key")
+
+	key, _ := cache.key(pkg, linter)
+	filename := cache.filename(key)
+	raw, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	entry, err := decodeCacheEntry(raw)
+	require.NoError(t, err)
+	require.NotNil(t, entry.Expires)
+	assert.Equal(t, 2999, entry.Expires.Year(), "entries expire with the directives")
+
+	expired := time.Now().Add(-time.Hour)
+	entry.Expires = &expired
+	raw, err = encodeCacheEntry(*entry)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filename, raw, 0644))
+	_, ok = cache.Get(pkg, linter)
+	assert.False(t, ok, "expired entries are ignored")
+
+	raw[len(raw)-2] ^= 1
+	require.NoError(t, ioutil.WriteFile(filename, raw, 0644))
+	_, ok = cache.Get(pkg, linter)
+	assert.False(t, ok, "corrupted entries are ignored")
+	_, err = os.Stat(filename)
+	assert.True(t, os.IsNotExist(err), "corrupted entries are removed")
+
+	cache.Put(pkg, linter, problems)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "other"), nil, 0644))
+	saved := params.cacheDir
+	defer func() { params.cacheDir = saved }()
+	params.cacheDir = dir
+	require.NoError(t, cleanCache())
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, "other", infos[0].Name(), "files which scopelint does not make are kept")
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// codeClimateReporter reports active problems in a JSON array of Code Climate issues,
+// which GitLab shows as a code quality report.
+type codeClimateReporter struct {
+	w            io.Writer
+	started      bool
+	fingerprints map[string]int // occurrences of each problem fingerprint
+}
+
+type codeClimateIssue struct {
+	Type        string              `json:"type"`
+	CheckName   string              `json:"check_name"`
+	Description string              `json:"description"`
+	Categories  []string            `json:"categories"`
+	Severity    string              `json:"severity"`
+	Fingerprint string              `json:"fingerprint"`
+	Location    codeClimateLocation `json:"location"`
+}
+
+type codeClimateLocation struct {
+	Path  string           `json:"path"`
+	Lines codeClimateLines `json:"lines"`
+}
+
+type codeClimateLines struct {
+	Begin int `json:"begin"`
+	End   int `json:"end,omitempty"`
+}
+
+func newCodeClimateReporter(w io.Writer) *codeClimateReporter {
+	return &codeClimateReporter{w: w, fingerprints: map[string]int{}}
+}
+
+func (r *codeClimateReporter) Report(_ string, problems []scopelint.Problem) error {
+	for _, p := range problems {
+		if p.Ignored {
+			continue
+		}
+		if err := r.write(r.issue(p)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReportError reports an error as a blocker issue whose check is the class of the error.
+func (r *codeClimateReporter) ReportError(e lintError) error {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s", e.Class, e.Package, e.Message)))
+	return r.write(codeClimateIssue{
+		Type:        "issue",
+		CheckName:   e.Class,
+		Description: e.Message,
+		Categories:  []string{"Bug Risk"},
+		Severity:    "blocker",
+		Fingerprint: r.fingerprint(hex.EncodeToString(sum[:])),
+		Location:    codeClimateLocation{Path: ".", Lines: codeClimateLines{Begin: 1}},
+	})
+}
+
+func (r *codeClimateReporter) write(issue codeClimateIssue) error {
+	delimiter := ",\n"
+	if !r.started {
+		r.started = true
+		delimiter = "[\n"
+	}
+	raw, err := json.Marshal(issue)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(r.w, "%s%s", delimiter, raw)
+	return err
+}
+
+func (r *codeClimateReporter) Close() error {
+	if !r.started {
+		_, err := fmt.Fprintln(r.w, "[]")
+		return err
+	}
+	_, err := fmt.Fprintln(r.w, "\n]")
+	return err
+}
+
+func (r *codeClimateReporter) issue(p scopelint.Problem) codeClimateIssue {
+	path, _ := relativePath(p.Position.Filename)
+	issue := codeClimateIssue{
+		Type:        "issue",
+		CheckName:   p.Category,
+		Description: p.Text,
+		Categories:  []string{"Bug Risk"},
+		Severity:    codeClimateSeverity(problemSeverity(p)),
+		Fingerprint: r.fingerprint(p.Fingerprint()),
+		Location:    codeClimateLocation{Path: path, Lines: codeClimateLines{Begin: p.Position.Line}},
+	}
+	if p.End.Line > p.Position.Line {
+		issue.Location.Lines.End = p.End.Line
+	}
+	return issue
+}
+
+// fingerprint returns the fingerprint of the issue which is stable across runs and line shifts.
+// Issues sharing a fingerprint (e.g. the same variable used twice in a line) are
+// distinguished by the order of their occurrence.
+func (r *codeClimateReporter) fingerprint(fingerprint string) string {
+	n := r.fingerprints[fingerprint]
+	r.fingerprints[fingerprint] = n + 1
+	if n == 0 {
+		return fingerprint
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s#%d", fingerprint, n)))
+	return hex.EncodeToString(sum[:])
+}
+
+func codeClimateSeverity(severity string) string {
+	switch severity {
+	case severityWarning:
+		return "minor"
+	case severityInfo:
+		return "info"
+	}
+	return "major"
+}
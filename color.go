@@ -0,0 +1,31 @@
+package main
+
+import "strings"
+
+// ansiCodes are SGR parameters of ANSI escape sequences for the names of colors and styles.
+var ansiCodes = map[string]string{
+	"bold":    "1",
+	"faint":   "2",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"gray":    "90",
+}
+
+// colorize decorates the text with ANSI escape sequences for the colors and styles
+// separated by spaces like "bold red". Unknown names are ignored.
+func colorize(names string, text string) string {
+	var codes []string
+	for _, name := range strings.Fields(names) {
+		if code, ok := ansiCodes[name]; ok {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 || text == "" {
+		return text
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m" + text + "\x1b[0m"
+}
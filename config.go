@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// configFilename is the name of the project configuration file.
+// It is discovered upward from each package directory,
+// and nested ones override parent ones.
+const configFilename = ".scopelint.json"
+
+// configFile is the content of a project configuration file.
+type configFile struct {
+	Enable    []string          `json:"enable,omitempty"`    // categories to report; empty means all of them
+	Disable   []string          `json:"disable,omitempty"`   // categories not to report
+	Severity  map[string]string `json:"severity,omitempty"`  // category => "error", "warning" or "info"
+	Exclude   []string          `json:"exclude,omitempty"`   // patterns of file paths relative to the configuration file
+	Callbacks []string          `json:"callbacks,omitempty"` // functions calling their function-literal arguments before returning
+	Go        *string           `json:"go,omitempty"`        // language version of the source code
+	Format    *string           `json:"format,omitempty"`    // output format
+	Test      *bool             `json:"test,omitempty"`      // search lints in the `*_test.go` files
+	Vendor    *bool             `json:"vendor,omitempty"`    // search lints in the `vendor` directories
+}
+
+// config is an effective configuration merged from configuration files.
+type config struct {
+	configFile
+
+	excludes []excludePattern
+	origins  map[string]string // key => filename which the value came from
+}
+
+type excludePattern struct {
+	dir     string // directory of the configuration file
+	pattern string
+}
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+var (
+	configCache = map[string]*config{}
+	configMu    sync.Mutex
+)
+
+// loadConfig returns the effective configuration for the directory.
+// It is safe for concurrent use.
+func loadConfig(dir string) (*config, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, &configError{err}
+	}
+	configMu.Lock()
+	defer configMu.Unlock()
+	cfg, err := loadConfigAbs(abs)
+	if err != nil {
+		return nil, &configError{err}
+	}
+	return cfg, nil
+}
+
+func loadConfigAbs(abs string) (*config, error) {
+	if cfg, ok := configCache[abs]; ok {
+		return cfg, nil
+	}
+
+	var parent *config
+	var err error
+	if up := filepath.Dir(abs); up != abs {
+		parent, err = loadConfigAbs(up)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		parent = &config{origins: map[string]string{}}
+	}
+
+	cfg := parent
+	filename := filepath.Join(abs, configFilename)
+	raw, err := ioutil.ReadFile(filename)
+	switch {
+	case err == nil:
+		var file configFile
+		if err := json.Unmarshal(raw, &file); err != nil {
+			return nil, fmt.Errorf("invalid configuration %s: %v", filename, err)
+		}
+		if err := file.validate(); err != nil {
+			return nil, fmt.Errorf("invalid configuration %s: %v", filename, err)
+		}
+		cfg = parent.merge(filename, &file)
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+	configCache[abs] = cfg
+	return cfg, nil
+}
+
+func (f *configFile) validate() error {
+	for category, severity := range f.Severity {
+		switch severity {
+		case severityError, severityWarning, severityInfo:
+		default:
+			return fmt.Errorf("unknown severity %q for %s", severity, category)
+		}
+	}
+	if f.Format != nil && !isFormat(*f.Format) {
+		return fmt.Errorf("unknown format %q", *f.Format)
+	}
+	for _, pattern := range f.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// merge returns a new configuration overridden by the file.
+func (c *config) merge(filename string, file *configFile) *config {
+	merged := &config{
+		configFile: c.configFile,
+		excludes:   c.excludes,
+		origins:    map[string]string{},
+	}
+	for key, origin := range c.origins {
+		merged.origins[key] = origin
+	}
+	set := func(key string) { merged.origins[key] = filename }
+
+	if file.Enable != nil {
+		merged.Enable = file.Enable
+		set("enable")
+	}
+	if file.Disable != nil {
+		merged.Disable = file.Disable
+		set("disable")
+	}
+	if len(file.Severity) > 0 {
+		merged.Severity = map[string]string{}
+		for category, severity := range c.Severity {
+			merged.Severity[category] = severity
+		}
+		for category, severity := range file.Severity {
+			merged.Severity[category] = severity
+			set("severity." + category)
+		}
+	}
+	if file.Exclude != nil {
+		merged.Exclude = append(append([]string{}, c.Exclude...), file.Exclude...)
+		merged.excludes = append([]excludePattern{}, c.excludes...)
+		for _, pattern := range file.Exclude {
+			merged.excludes = append(merged.excludes, excludePattern{dir: filepath.Dir(filename), pattern: pattern})
+		}
+		set("exclude")
+	}
+	if file.Callbacks != nil {
+		merged.Callbacks = file.Callbacks
+		set("callbacks")
+	}
+	if file.Go != nil {
+		merged.Go = file.Go
+		set("go")
+	}
+	if file.Format != nil {
+		merged.Format = file.Format
+		set("format")
+	}
+	if file.Test != nil {
+		merged.Test = file.Test
+		set("test")
+	}
+	if file.Vendor != nil {
+		merged.Vendor = file.Vendor
+		set("vendor")
+	}
+	return merged
+}
+
+// enabled reports whether the category of problems is reported.
+func (c *config) enabled(category string) bool {
+	for _, disabled := range c.Disable {
+		if disabled == category {
+			return false
+		}
+	}
+	if len(c.Enable) == 0 {
+		return true
+	}
+	for _, enabled := range c.Enable {
+		if enabled == category {
+			return true
+		}
+	}
+	return false
+}
+
+// severity returns the severity of the category of problems.
+func (c *config) severity(category string) string {
+	if severity, ok := c.Severity[category]; ok {
+		return severity
+	}
+	return severityError
+}
+
+// excluded reports whether the file is excluded from linting.
+// A pattern matches the path relative to its configuration file, any parent directory of it,
+// or the base name if the pattern has no slash.
+func (c *config) excluded(filename string) bool {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return false
+	}
+	for _, e := range c.excludes {
+		rel, err := filepath.Rel(e.dir, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if !strings.Contains(e.pattern, "/") {
+			if matched, _ := path.Match(e.pattern, path.Base(rel)); matched {
+				return true
+			}
+		}
+		for p := rel; p != "."; p = path.Dir(p) {
+			if matched, _ := path.Match(e.pattern, p); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *config) goVersion() string {
+	if c.Go == nil {
+		return ""
+	}
+	return *c.Go
+}
+
+func (c *config) test() bool {
+	if params.testSet || c.Test == nil {
+		return params.test
+	}
+	return *c.Test
+}
+
+func (c *config) vendor() bool {
+	if params.vendorSet || c.Vendor == nil {
+		return params.vendor
+	}
+	return *c.Vendor
+}
+
+func (c *config) format() string {
+	switch {
+	case params.templateFile != "":
+		return templateFormat
+	case params.formatSet:
+		return params.format
+	case c.Format != nil:
+		return *c.Format
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return "github"
+	}
+	return defaultFormat
+}
+
+// languageVersion returns the language version of the source code in the directory:
+// the configured one, or the one in the go.mod file of the module.
+func (c *config) languageVersion(dir string) string {
+	if version := c.goVersion(); version != "" {
+		return version
+	}
+	if mod, err := scopelint.FindModule(dir); err == nil && mod != nil {
+		return mod.GoVersion
+	}
+	return ""
+}
+
+// linter returns a linter for the loaded package.
+func (c *config) linter(pkg *scopelint.LoadedPackage) *scopelint.Linter {
+	version := c.goVersion()
+	if version == "" {
+		version = pkg.Build.GoVersion
+	}
+	return &scopelint.Linter{
+		GoVersion: version,
+		Callbacks: c.Callbacks,
+	}
+}
+
+// filter removes problems in the disabled categories.
+func (c *config) filter(problems []scopelint.Problem) []scopelint.Problem {
+	var filtered []scopelint.Problem
+	for _, p := range problems {
+		if c.enabled(p.Category) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+func printConfig(w io.Writer, dir string) error {
+	cfg, err := loadConfig(dir)
+	if err != nil {
+		return err
+	}
+
+	type entry struct{ key, value string }
+	var entries []entry
+	add := func(key string, value interface{}) {
+		if list, ok := value.([]string); ok && list == nil {
+			value = []string{}
+		}
+		raw, _ := json.Marshal(value)
+		entries = append(entries, entry{key: key, value: string(raw)})
+	}
+	add("enable", cfg.Enable)
+	add("disable", cfg.Disable)
+	categories := make([]string, 0, len(cfg.Severity))
+	for category := range cfg.Severity {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		add("severity."+category, cfg.Severity[category])
+	}
+	add("exclude", cfg.Exclude)
+	add("callbacks", cfg.Callbacks)
+	add("go", cfg.languageVersion(dir))
+	var goOrigin string
+	if _, ok := cfg.origins["go"]; !ok {
+		if mod, err := scopelint.FindModule(dir); err == nil && mod != nil && mod.GoVersion != "" {
+			goOrigin = mod.GoMod
+		}
+	}
+	add("format", cfg.format())
+	add("test", cfg.test())
+	add("vendor", cfg.vendor())
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, e := range entries {
+		origin, ok := cfg.origins[e.key]
+		switch {
+		case (e.key == "test" && params.testSet) || (e.key == "vendor" && params.vendorSet) || (e.key == "format" && (params.formatSet || params.templateFile != "")):
+			origin = "(flag)"
+		case e.key == "go" && goOrigin != "":
+			origin = goOrigin
+		case !ok:
+			origin = "(default)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.key, e.value, origin)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	root, err := ioutil.TempDir("", "scopelint-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	sub := filepath.Join(root, "sub")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, configFilename), []byte(`{
+	"severity": {"range-scope": "warning"},
+	"exclude": ["gen_*.go", "testdata"],
+	"test": false
+}`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sub, configFilename), []byte(`{
+	"disable": ["range-scope"],
+	"severity": {"other": "info"},
+	"go": "1.22"
+}`), 0644))
+
+	cfg, err := loadConfig(sub)
+	require.NoError(t, err)
+	assert.False(t, cfg.enabled("range-scope"))
+	assert.True(t, cfg.enabled("other"))
+	assert.Equal(t, severityWarning, cfg.severity("range-scope"))
+	assert.Equal(t, severityInfo, cfg.severity("other"))
+	assert.Equal(t, "1.22", cfg.goVersion())
+	assert.False(t, cfg.test())
+	assert.Equal(t, filepath.Join(root, configFilename), cfg.origins["severity.range-scope"])
+	assert.Equal(t, filepath.Join(sub, configFilename), cfg.origins["severity.other"])
+
+	assert.True(t, cfg.excluded(filepath.Join(sub, "gen_foo.go")))
+	assert.True(t, cfg.excluded(filepath.Join(root, "testdata", "foo.go")))
+	assert.False(t, cfg.excluded(filepath.Join(sub, "foo.go")))
+
+	parent, err := loadConfig(root)
+	require.NoError(t, err)
+	assert.True(t, parent.enabled("range-scope"))
+	assert.Equal(t, "", parent.goVersion())
+
+	broken := filepath.Join(root, "broken")
+	require.NoError(t, os.MkdirAll(broken, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(broken, configFilename), []byte(`{"severity": {"range-scope": "fatal"}}`), 0644))
+	_, err = loadConfig(broken)
+	assert.Error(t, err)
+}
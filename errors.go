@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"go/scanner"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Classes of failures of a run, which the --fail-on flag chooses.
+const (
+	classFindings = "findings" // active problems are found
+	classLoad     = "load"     // packages or files cannot be loaded
+	classParse    = "parse"    // files cannot be parsed
+	classConfig   = "config"   // the configuration files or the command line are invalid
+)
+
+// classes are the failure classes in the order of their exit statuses.
+var classes = []string{classFindings, classLoad, classParse, classConfig}
+
+// Exit statuses.
+const (
+	exitClean    = 0
+	exitFindings = 1
+	exitErrors   = 2 // load or parse errors
+	exitConfig   = 3
+)
+
+func exitStatus(class string) int {
+	switch class {
+	case classFindings:
+		return exitFindings
+	case classConfig:
+		return exitConfig
+	}
+	return exitErrors
+}
+
+// configError is an error in the configuration files or the command line.
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string { return e.err.Error() }
+
+// lintError is an error in a run, which is reported in the summary and structured outputs.
+type lintError struct {
+	Class   string `json:"class"`
+	Package string `json:"package,omitempty"`
+	Message string `json:"message"`
+}
+
+// newLintError classifies the error found in the package.
+// Errors from the loader are load errors unless they are in the configuration or the syntax,
+// and the others are parse errors.
+func newLintError(pkg string, err error, loading bool) lintError {
+	class := classParse
+	switch err.(type) {
+	case *configError:
+		class = classConfig
+	case scanner.ErrorList, *scanner.Error:
+	default:
+		if loading {
+			class = classLoad
+		}
+	}
+	return lintError{Class: class, Package: pkg, Message: err.Error()}
+}
+
+// errorList collects errors in a run.
+type errorList struct {
+	mu     sync.Mutex
+	errors []lintError
+}
+
+// runErrors is the errors in the run.
+var runErrors errorList
+
+// add prints the error to stderr and collects it.
+func (l *errorList) add(e lintError) {
+	fmt.Fprintln(os.Stderr, e.Message)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, e)
+}
+
+func (l *errorList) list() []lintError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]lintError(nil), l.errors...)
+}
+
+// report reports the errors to the reporter, if it supports errors.
+func (l *errorList) report(r reporter) error {
+	for _, e := range l.list() {
+		if err := reportError(r, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errorSummary returns the numbers of the errors in each class like "3 errors (2 load, 1 parse)".
+func errorSummary(errs []lintError) string {
+	counts := map[string]int{}
+	for _, e := range errs {
+		counts[e.Class]++
+	}
+	var parts []string
+	for _, class := range classes {
+		if counts[class] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[class], class))
+		}
+	}
+	noun := "errors"
+	if len(errs) == 1 {
+		noun = "error"
+	}
+	return fmt.Sprintf("%d %s (%s)", len(errs), noun, strings.Join(parts, ", "))
+}
+
+// failOn is a set of the failure classes which fail the run.
+type failOn map[string]bool
+
+// parseFailOn parses a value of the --fail-on flag: failure classes separated by commas,
+// "errors" for load and parse errors, "all" or "none".
+func parseFailOn(value string) (failOn, error) {
+	f := failOn{}
+	for _, class := range strings.Split(value, ",") {
+		switch class = strings.TrimSpace(class); class {
+		case "", "none":
+		case "all":
+			for _, c := range classes {
+				f[c] = true
+			}
+		case "errors":
+			f[classLoad] = true
+			f[classParse] = true
+		case classFindings, classLoad, classParse, classConfig:
+			f[class] = true
+		default:
+			return nil, &configError{fmt.Errorf("invalid --fail-on %q: unknown class %q", value, class)}
+		}
+	}
+	return f, nil
+}
+
+// status returns the exit status for the active problems and the errors:
+// the highest one of the classes which are found and fail the run.
+func (f failOn) status(active int, errs []lintError) int {
+	found := map[string]bool{classFindings: active > 0}
+	for _, e := range errs {
+		found[e.Class] = true
+	}
+	status := exitClean
+	for _, class := range classes {
+		if found[class] && f[class] && exitStatus(class) > status {
+			status = exitStatus(class)
+		}
+	}
+	return status
+}
+
+// fatal prints the error which stops the run, and exits with the status of its class.
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	if _, ok := err.(*configError); ok {
+		os.Exit(exitConfig)
+	}
+	os.Exit(exitErrors)
+}
+
+// finish prints the summary of the active problems and the errors in the run,
+// and exits with the status following the --fail-on and --set-exit-status flags.
+func finish(active int, errs []lintError) {
+	f, err := parseFailOn(params.failOn)
+	if err != nil {
+		fatal(err)
+	}
+	if !params.setExitStatus {
+		delete(f, classFindings)
+	}
+	if len(errs) > 0 {
+		suffix := ""
+		for _, e := range errs {
+			if f[e.Class] {
+				suffix = "; failing"
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Found %s%s.\n", errorSummary(errs), suffix)
+	}
+	if active > 0 && f[classFindings] {
+		fmt.Fprintf(os.Stderr, "Found %d lint problems; failing.\n", active)
+	}
+	os.Exit(f.status(active, errs))
+}
+
+// errorReporter is a reporter which reports errors in the run too.
+// Reporters which do not implement it report only problems.
+type errorReporter interface {
+	// ReportError reports an error. It is called after all packages are reported, before Close.
+	ReportError(e lintError) error
+}
+
+func reportError(r reporter, e lintError) error {
+	if er, ok := r.(errorReporter); ok {
+		return er.ReportError(e)
+	}
+	return nil
+}
+
+// sortErrors sorts errors by their packages, classes and messages.
+func sortErrors(errs []lintError) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		ei, ej := errs[i], errs[j]
+		if ei.Package != ej.Package {
+			return ei.Package < ej.Package
+		}
+		if ei.Class != ej.Class {
+			return ei.Class < ej.Class
+		}
+		return ei.Message < ej.Message
+	})
+}
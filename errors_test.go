@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLintError(t *testing.T) {
+	_, parseErr := parser.ParseFile(token.NewFileSet(), "a.go", "package", 0)
+	require.Error(t, parseErr)
+
+	assert.Equal(t, classLoad, newLintError("p", errors.New("cannot find package"), true).Class)
+	assert.Equal(t, classParse, newLintError("p", errors.New("a.go is in package a, not b"), false).Class)
+	assert.Equal(t, classParse, newLintError("p", parseErr, true).Class, "syntax errors in loading are parse errors")
+	assert.Equal(t, classConfig, newLintError("p", &configError{errors.New("invalid configuration")}, true).Class)
+	assert.Equal(t, "p", newLintError("p", parseErr, false).Package)
+}
+
+func TestFailOn(t *testing.T) {
+	load := lintError{Class: classLoad, Message: "load"}
+	config := lintError{Class: classConfig, Message: "config"}
+
+	all, err := parseFailOn("all")
+	require.NoError(t, err)
+	assert.Equal(t, exitClean, all.status(0, nil))
+	assert.Equal(t, exitFindings, all.status(1, nil))
+	assert.Equal(t, exitErrors, all.status(1, []lintError{load}))
+	assert.Equal(t, exitConfig, all.status(1, []lintError{load, config}), "the highest status is used")
+
+	f, err := parseFailOn("findings, errors")
+	require.NoError(t, err)
+	assert.Equal(t, failOn{classFindings: true, classLoad: true, classParse: true}, f)
+	assert.Equal(t, exitFindings, f.status(1, []lintError{config}))
+
+	none, err := parseFailOn("none")
+	require.NoError(t, err)
+	assert.Equal(t, exitClean, none.status(1, []lintError{load, config}))
+
+	_, err = parseFailOn("findings,warnings")
+	require.Error(t, err)
+	assert.IsType(t, &configError{}, err)
+
+	assert.Equal(t, "3 errors (2 load, 1 config)", errorSummary([]lintError{load, config, load}))
+	assert.Equal(t, "1 error (1 load)", errorSummary([]lintError{load}))
+}
+
+func TestReportErrors(t *testing.T) {
+	e := lintError{Class: classLoad, Package: "example.com/m/p", Message: "cannot find package"}
+
+	var buf bytes.Buffer
+	r := &jsonReporter{w: &buf}
+	require.NoError(t, r.Report("example.com/m/q", nil))
+	require.NoError(t, reportError(r, e))
+	require.NoError(t, r.Close())
+	var report jsonReport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+	assert.Equal(t, []lintError{e}, report.Errors)
+	assert.Equal(t, jsonTotals{Packages: 1, Errors: 1}, report.Totals)
+
+	buf.Reset()
+	sarif := &sarifReporter{w: &buf}
+	require.NoError(t, reportError(sarif, e))
+	require.NoError(t, sarif.Close())
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	require.Len(t, log.Runs[0].Invocations, 1)
+	invocation := log.Runs[0].Invocations[0]
+	assert.False(t, invocation.ExecutionSuccessful)
+	require.Len(t, invocation.ToolExecutionNotifications, 1)
+	assert.Equal(t, "cannot find package", invocation.ToolExecutionNotifications[0].Message.Text)
+	assert.Equal(t, classLoad, invocation.ToolExecutionNotifications[0].Descriptor.ID)
+
+	buf.Reset()
+	outputs := multiReporter{&confidenceFilter{reporter: &githubReporter{w: &buf}, min: 0.5}, newTextReporter(&buf, false)}
+	require.NoError(t, reportError(outputs, e))
+	assert.Equal(t, "::error title=scopelint(load)::cannot find package\n", buf.String(), "errors are forwarded to reporters supporting them")
+
+	buf.Reset()
+	checkstyle := newCheckstyleReporter(&buf, false)
+	require.NoError(t, reportError(checkstyle, e))
+	require.NoError(t, checkstyle.Close())
+	assert.Contains(t, buf.String(), `<file name="example.com/m/p">`)
+	assert.Contains(t, buf.String(), `<error line="0" column="0" severity="error" message="cannot find package" source="scopelint.load"></error>`)
+
+	buf.Reset()
+	codeClimate := newCodeClimateReporter(&buf)
+	require.NoError(t, reportError(codeClimate, e))
+	require.NoError(t, codeClimate.Close())
+	var issues []codeClimateIssue
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &issues))
+	require.Len(t, issues, 1)
+	assert.Equal(t, classLoad, issues[0].CheckName)
+	assert.Equal(t, "cannot find package", issues[0].Description)
+	assert.NotEmpty(t, issues[0].Fingerprint)
+
+	buf.Reset()
+	require.NoError(t, reportError(newRDJSONLReporter(&buf, false), e))
+	var diagnostic rdDiagnostic
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &diagnostic))
+	assert.Equal(t, "example.com/m/p: cannot find package", diagnostic.Message)
+	assert.Equal(t, "ERROR", diagnostic.Severity)
+	assert.Equal(t, classLoad, diagnostic.Code.Value)
+
+	buf.Reset()
+	html := newHTMLReporter(&buf)
+	require.NoError(t, reportError(html, e))
+	require.NoError(t, html.Close())
+	assert.Contains(t, buf.String(), `<h2>Errors</h2>`)
+	assert.Contains(t, buf.String(), `<span class="badge error">load</span> <span class="location">example.com/m/p</span> cannot find package`)
+
+	buf.Reset()
+	markdown := newMarkdownReporter(&buf, false)
+	require.NoError(t, markdown.Report("example.com/m/q", nil))
+	require.NoError(t, reportError(markdown, e))
+	require.NoError(t, markdown.Close())
+	assert.Equal(t, "## scopelint\n\nFound 1 error (1 load).\n\n- **load** `example.com/m/p`: cannot find package\n\n", buf.String(),
+		"runs with errors are not reported as clean")
+}
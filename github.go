@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// lowConfidence is the threshold of the confidence under which problems are reported as warnings.
+const lowConfidence = 0.8
+
+// githubReporter reports problems in workflow commands of GitHub Actions,
+// which are shown as annotations on pull requests.
+type githubReporter struct {
+	w           io.Writer
+	showIgnored bool
+}
+
+func (r *githubReporter) Report(_ string, problems []scopelint.Problem) error {
+	for _, p := range problems {
+		if p.Ignored && !r.showIgnored {
+			continue
+		}
+		file, _ := relativePath(p.Position.Filename)
+		properties := []string{
+			"file=" + escapeGitHubProperty(file),
+			fmt.Sprintf("line=%d", p.Position.Line),
+			fmt.Sprintf("col=%d", p.Position.Column),
+		}
+		if p.End.Line > 0 {
+			properties = append(properties,
+				fmt.Sprintf("endLine=%d", p.End.Line),
+				fmt.Sprintf("endColumn=%d", p.End.Column),
+			)
+		}
+		properties = append(properties, "title="+escapeGitHubProperty("scopelint("+p.Category+")"))
+		message := p.Text
+		if p.Ignored {
+			message += " (ignored)"
+		}
+		if _, err := fmt.Fprintf(r.w, "::%s %s::%s\n", githubCommand(p), strings.Join(properties, ","), escapeGitHubData(message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *githubReporter) ReportError(e lintError) error {
+	title := escapeGitHubProperty("scopelint(" + e.Class + ")")
+	_, err := fmt.Fprintf(r.w, "::error title=%s::%s\n", title, escapeGitHubData(e.Message))
+	return err
+}
+
+func (r *githubReporter) Close() error { return nil }
+
+// githubCommand returns the workflow command for the problem.
+func githubCommand(p scopelint.Problem) string {
+	if p.Ignored {
+		return "notice"
+	}
+	switch problemSeverity(p) {
+	case severityInfo:
+		return "notice"
+	case severityWarning:
+		return "warning"
+	}
+	if p.Confidence < lowConfidence {
+		return "warning"
+	}
+	return "error"
+}
+
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
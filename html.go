@@ -0,0 +1,309 @@
+package main
+
+import (
+	"go/scanner"
+	"go/token"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// htmlContext is the number of source lines shown around each problem in HTML reports.
+const htmlContext = 3
+
+// htmlReporter reports problems in a self-contained HTML document.
+// All problems are buffered and written on Close.
+type htmlReporter struct {
+	w        io.Writer
+	packages []htmlPackage
+	errors   []lintError
+	sources  map[string][]template.HTML // highlighted lines of source files
+}
+
+type htmlPackage struct {
+	Name     string
+	Active   int
+	Ignored  int
+	Problems []htmlProblem
+}
+
+type htmlProblem struct {
+	scopelint.Problem
+	Filename string
+	Severity string
+	Excerpt  []htmlLine
+	Expires  string
+}
+
+type htmlLine struct {
+	Number  int
+	Code    template.HTML
+	Class   string // "hit" for the line of the problem, "related" for related locations
+	Message string
+}
+
+func newHTMLReporter(w io.Writer) *htmlReporter {
+	return &htmlReporter{w: w, sources: map[string][]template.HTML{}}
+}
+
+func (r *htmlReporter) Report(pkg string, problems []scopelint.Problem) error {
+	p := htmlPackage{Name: pkg}
+	for _, problem := range problems {
+		if problem.Ignored {
+			p.Ignored++
+		} else {
+			p.Active++
+		}
+		p.Problems = append(p.Problems, r.problem(problem))
+	}
+	r.packages = append(r.packages, p)
+	return nil
+}
+
+func (r *htmlReporter) ReportError(e lintError) error {
+	r.errors = append(r.errors, e)
+	return nil
+}
+
+func (r *htmlReporter) Close() error {
+	categories := map[string]bool{}
+	totals := jsonTotals{Errors: len(r.errors)}
+	for _, p := range r.packages {
+		totals.Packages++
+		totals.Active += p.Active
+		totals.Ignored += p.Ignored
+		for _, problem := range p.Problems {
+			categories[problem.Category] = true
+		}
+	}
+	var names []string
+	for category := range categories {
+		names = append(names, category)
+	}
+	sort.Strings(names)
+	return htmlTemplate.Execute(r.w, map[string]interface{}{
+		"Tool":       tool,
+		"Totals":     totals,
+		"Categories": names,
+		"Packages":   r.packages,
+		"Errors":     r.errors,
+	})
+}
+
+func (r *htmlReporter) problem(p scopelint.Problem) htmlProblem {
+	filename, _ := relativePath(p.Position.Filename)
+	problem := htmlProblem{
+		Problem:  p,
+		Filename: filename,
+		Severity: problemSeverity(p),
+	}
+	if p.Ignored {
+		problem.Severity = "ignored"
+	}
+	if p.Directive != nil && !p.Directive.Expires.IsZero() {
+		problem.Expires = p.Directive.Expires.Format("2006-01-02")
+	}
+
+	lines := r.highlight(p.Position.Filename)
+	if p.Position.Line < 1 || p.Position.Line > len(lines) {
+		return problem
+	}
+	first, last := p.Position.Line-htmlContext, p.Position.Line+htmlContext
+	messages := map[int]string{}
+	for _, related := range p.Related {
+		if related.Position.Filename == p.Position.Filename && related.Position.Line >= 1 {
+			messages[related.Position.Line] = related.Message
+			if related.Position.Line < first {
+				first = related.Position.Line
+			}
+		}
+	}
+	if first < 1 {
+		first = 1
+	}
+	if last > len(lines) {
+		last = len(lines)
+	}
+	for n := first; n <= last; n++ {
+		line := htmlLine{Number: n, Code: lines[n-1]}
+		if message, ok := messages[n]; ok {
+			line.Class, line.Message = "related", message
+		}
+		if n == p.Position.Line {
+			line.Class, line.Message = "hit", ""
+		}
+		problem.Excerpt = append(problem.Excerpt, line)
+	}
+	return problem
+}
+
+// highlight returns the lines of the file in HTML with syntax highlighting.
+func (r *htmlReporter) highlight(filename string) []template.HTML {
+	if lines, ok := r.sources[filename]; ok {
+		return lines
+	}
+	src, err := sourceOverlay.readFile(filename)
+	if err != nil {
+		r.sources[filename] = nil
+		return nil
+	}
+	lines := highlightGo(src)
+	r.sources[filename] = lines
+	return lines
+}
+
+// highlightGo returns the lines of the Go source in HTML, with tokens wrapped in spans
+// of the classes "k" (keywords), "s" (strings), "n" (numbers) and "c" (comments).
+func highlightGo(src []byte) []template.HTML {
+	classes := make([]byte, len(src))
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, func(token.Position, string) {}, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		var class byte
+		switch {
+		case tok.IsKeyword():
+			class, lit = 'k', tok.String()
+		case tok == token.STRING || tok == token.CHAR:
+			class = 's'
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			class = 'n'
+		case tok == token.COMMENT:
+			class = 'c'
+		default:
+			continue
+		}
+		start := file.Offset(pos)
+		for i := start; i < start+len(lit) && i < len(classes); i++ {
+			classes[i] = class
+		}
+	}
+
+	// Runs of bytes in a class are escaped at once, so that multi-byte characters are kept.
+	var lines []template.HTML
+	var b strings.Builder
+	var open byte
+	start := 0
+	for i := 0; i <= len(src); i++ {
+		if i == len(src) || src[i] == '\n' {
+			b.WriteString(template.HTMLEscapeString(string(src[start:i])))
+			start = i + 1
+			if open != 0 {
+				b.WriteString("</span>")
+				open = 0
+			}
+			lines = append(lines, template.HTML(b.String()))
+			b.Reset()
+			continue
+		}
+		if classes[i] != open {
+			b.WriteString(template.HTMLEscapeString(string(src[start:i])))
+			start = i
+			if open != 0 {
+				b.WriteString("</span>")
+			}
+			if classes[i] != 0 {
+				b.WriteString(`<span class="` + string(classes[i]) + `">`)
+			}
+			open = classes[i]
+		}
+	}
+	if len(lines) > 0 && len(src) > 0 && src[len(src)-1] == '\n' {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>scopelint report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292e; }
+header { position: sticky; top: 0; background: #fff; padding: .5em 0; border-bottom: 1px solid #e1e4e8; }
+header label { margin-right: 1em; }
+h2 { border-bottom: 1px solid #e1e4e8; padding-bottom: .3em; }
+.problem { border: 1px solid #e1e4e8; border-radius: 6px; margin: 1em 0; padding: .5em 1em; }
+.problem.ignored { opacity: .6; }
+.run-error { border: 1px solid #d73a49; border-radius: 6px; margin: 1em 0; padding: .5em 1em; }
+.badge { display: inline-block; border-radius: 1em; padding: 0 .6em; font-size: 85%; color: #fff; background: #6a737d; }
+.badge.error { background: #d73a49; }
+.badge.warning { background: #b08800; }
+.badge.info { background: #0366d6; }
+.location { font-family: monospace; }
+table.excerpt { border-collapse: collapse; font-family: monospace; width: 100%; }
+table.excerpt td { white-space: pre; tab-size: 4; padding: 0 .5em; vertical-align: top; }
+table.excerpt td.number { color: #959da5; text-align: right; user-select: none; width: 1%; }
+tr.hit { background: #ffeef0; }
+tr.related { background: #f1f8ff; }
+td.message { color: #0366d6; font-family: sans-serif; }
+.k { color: #d73a49; } .s { color: #032f62; } .n { color: #005cc5; } .c { color: #6a737d; }
+.directive { background: #f6f8fa; padding: .3em .6em; margin-top: .5em; }
+.fix { color: #22863a; }
+</style>
+</head>
+<body>
+<h1>scopelint report</h1>
+<p>{{.Tool.Name}} {{.Tool.Version}}: {{.Totals.Active}} active and {{.Totals.Ignored}} ignored problems in {{.Totals.Packages}} packages{{if .Errors}}, and {{.Totals.Errors}} errors{{end}}</p>
+<header>
+{{range .Categories}}<label><input type="checkbox" class="category" value="{{.}}" checked> {{.}}</label>
+{{end}}<label>Minimum confidence <select id="confidence">
+<option value="0" selected>any</option>
+<option value="0.5">0.5</option>
+<option value="0.8">0.8</option>
+<option value="1">1.0</option>
+</select></label>
+<label><input type="checkbox" id="ignored" checked> ignored</label>
+</header>
+{{if .Errors}}<section class="errors">
+<h2>Errors</h2>
+{{range .Errors}}<div class="run-error"><p><span class="badge error">{{.Class}}</span> {{if .Package}}<span class="location">{{.Package}}</span> {{end}}{{.Message}}</p></div>
+{{end}}</section>
+{{end}}{{range .Packages}}{{if .Problems}}
+<section class="package">
+<h2>{{.Name}} <small>({{.Active}} active, {{.Ignored}} ignored)</small></h2>
+{{range .Problems}}<div class="problem{{if .Ignored}} ignored{{end}}" data-category="{{.Category}}" data-confidence="{{.Confidence}}" data-ignored="{{.Ignored}}">
+<p><span class="badge {{.Severity}}">{{.Severity}}</span> <span class="location">{{.Filename}}:{{.Position.Line}}:{{.Position.Column}}</span>
+<strong>{{.Category}}</strong>: {{.Text}}{{if lt .Confidence 1.0}} <small>(confidence {{.Confidence}})</small>{{end}}</p>
+{{if .Excerpt}}<table class="excerpt">
+{{range .Excerpt}}<tr{{if .Class}} class="{{.Class}}"{{end}}><td class="number">{{.Number}}</td><td>{{.Code}}</td>{{if .Message}}<td class="message">{{.Message}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}{{if .Ignored}}{{with .Directive}}<div class="directive">Ignored by a {{.Scope}} directive at {{.Position}}{{if .Rules}} for {{range $i, $r := .Rules}}{{if $i}}, {{end}}{{$r}}{{end}}{{end}}{{if .Justification}}: <em>{{.Justification}}</em>{{end}}</div>
+{{end}}{{if .Expires}}<div class="directive">Expires on {{.Expires}}</div>
+{{end}}{{else}}{{range .Fixes}}<p class="fix">Fix: {{.Message}}</p>
+{{end}}{{end}}</div>
+{{end}}</section>
+{{end}}{{end}}
+<script>
+(function () {
+  function update() {
+    var categories = {};
+    document.querySelectorAll("input.category").forEach(function (c) { categories[c.value] = c.checked; });
+    var confidence = parseFloat(document.getElementById("confidence").value);
+    var ignored = document.getElementById("ignored").checked;
+    document.querySelectorAll(".problem").forEach(function (p) {
+      var shown = categories[p.dataset.category] &&
+        parseFloat(p.dataset.confidence) >= confidence &&
+        (ignored || p.dataset.ignored !== "true");
+      p.style.display = shown ? "" : "none";
+    });
+    document.querySelectorAll("section.package").forEach(function (s) {
+      var any = Array.prototype.some.call(s.querySelectorAll(".problem"), function (p) { return p.style.display !== "none"; });
+      s.style.display = any ? "" : "none";
+    });
+  }
+  document.querySelectorAll("header input, header select").forEach(function (e) { e.addEventListener("change", update); });
+})();
+</script>
+</body>
+</html>
+`))
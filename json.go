@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// jsonReport is a report in JSON.
+// Problems are represented in the schema of scopelint.Problem, versioned by scopelint.SchemaVersion.
+type jsonReport struct {
+	Version  int           `json:"version"`
+	Tool     jsonTool      `json:"tool"`
+	Packages []jsonPackage `json:"packages"`
+	Errors   []lintError   `json:"errors"`
+	Totals   jsonTotals    `json:"totals"`
+}
+
+type jsonTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type jsonPackage struct {
+	Name     string              `json:"name"`
+	Problems []scopelint.Problem `json:"problems"`
+}
+
+type jsonTotals struct {
+	Packages int `json:"packages"`
+	Active   int `json:"active"`
+	Ignored  int `json:"ignored"`
+	Errors   int `json:"errors"`
+}
+
+func (t *jsonTotals) count(problems []scopelint.Problem) {
+	t.Packages++
+	for _, p := range problems {
+		if p.Ignored {
+			t.Ignored++
+		} else {
+			t.Active++
+		}
+	}
+}
+
+var tool = jsonTool{Name: "scopelint", Version: version}
+
+// jsonReporter streams a jsonReport.
+type jsonReporter struct {
+	w       io.Writer
+	started bool
+	errors  []lintError
+	totals  jsonTotals
+}
+
+func (r *jsonReporter) start() error {
+	if r.started {
+		return nil
+	}
+	r.started = true
+	header, err := json.Marshal(tool)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(r.w, "{\"version\":%d,\"tool\":%s,\"packages\":[", scopelint.SchemaVersion, header)
+	return err
+}
+
+func (r *jsonReporter) Report(pkg string, problems []scopelint.Problem) error {
+	delimiter := ","
+	if !r.started {
+		delimiter = ""
+	}
+	if err := r.start(); err != nil {
+		return err
+	}
+	if problems == nil {
+		problems = []scopelint.Problem{}
+	}
+	raw, err := json.Marshal(jsonPackage{Name: pkg, Problems: problems})
+	if err != nil {
+		return err
+	}
+	r.totals.count(problems)
+	_, err = fmt.Fprintf(r.w, "%s\n%s", delimiter, raw)
+	return err
+}
+
+func (r *jsonReporter) ReportError(e lintError) error {
+	r.errors = append(r.errors, e)
+	r.totals.Errors++
+	return nil
+}
+
+func (r *jsonReporter) Close() error {
+	if err := r.start(); err != nil {
+		return err
+	}
+	if r.errors == nil {
+		r.errors = []lintError{}
+	}
+	errors, err := json.Marshal(r.errors)
+	if err != nil {
+		return err
+	}
+	totals, err := json.Marshal(r.totals)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(r.w, "\n],\"errors\":%s,\"totals\":%s}\n", errors, totals)
+	return err
+}
+
+// jsonLinesReporter reports each problem in a line of JSON.
+type jsonLinesReporter struct {
+	enc *json.Encoder
+}
+
+type jsonLine struct {
+	Version int               `json:"version"`
+	Package string            `json:"package"`
+	Problem scopelint.Problem `json:"problem"`
+}
+
+func newJSONLinesReporter(w io.Writer) *jsonLinesReporter {
+	return &jsonLinesReporter{enc: json.NewEncoder(w)}
+}
+
+func (r *jsonLinesReporter) Report(pkg string, problems []scopelint.Problem) error {
+	for _, p := range problems {
+		if err := r.enc.Encode(jsonLine{Version: scopelint.SchemaVersion, Package: pkg, Problem: p}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonErrorLine is a line of an error in the run.
+type jsonErrorLine struct {
+	Version int       `json:"version"`
+	Error   lintError `json:"error"`
+}
+
+func (r *jsonLinesReporter) ReportError(e lintError) error {
+	return r.enc.Encode(jsonErrorLine{Version: scopelint.SchemaVersion, Error: e})
+}
+
+func (r *jsonLinesReporter) Close() error { return nil }
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// targets returns the loader and the patterns of the target packages in the arguments.
+func targets() (scopelint.Loader, []string) {
+	if len(params.arguments.files) > 0 {
+		return &configLoader{&scopelint.FilesLoader{ReadFile: sourceOverlay.readFile}}, params.arguments.files
+	}
+	var patterns []string
+	if params.arguments.stdin {
+		patterns = append(patterns, localPattern(filepath.Dir(params.stdinFilename)))
+	}
+	for _, dir := range params.arguments.directories {
+		patterns = append(patterns, localPattern(dir))
+	}
+	patterns = append(patterns, params.arguments.packages...)
+	return &configLoader{&scopelint.ModuleLoader{
+		BuildLoader: scopelint.BuildLoader{
+			Context:          &buildContext,
+			Tests:            true, // filtered by configLoader
+			AllBuildContexts: params.allBuildContexts,
+			Logf:             warnf,
+		},
+		Workspace: params.workspace,
+	}}, patterns
+}
+
+// enumerator is a loader which lists the packages matching patterns without loading them,
+// like scopelint.ModuleLoader.
+type enumerator interface {
+	ImportPaths(patterns ...string) ([]string, error)
+}
+
+// shardPatterns returns the packages matching the patterns which belong to the shard.
+// The files given in the arguments are a package, which belongs to the shard of its pseudo import path.
+func shardPatterns(loader scopelint.Loader, patterns []string, shard scopelint.Shard) ([]string, error) {
+	if l, ok := loader.(*configLoader); ok {
+		loader = l.Loader
+	}
+	e, ok := loader.(enumerator)
+	if !ok {
+		if shard.Contains(scopelint.CommandLineArguments) {
+			return patterns, nil
+		}
+		return nil, nil
+	}
+	names, err := e.ImportPaths(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	return shard.Filter(names), nil
+}
+
+// localPattern returns the pattern of the directory in the arguments,
+// which begins with "./" so that it is not taken as an import path.
+func localPattern(dir string) string {
+	if filepath.IsAbs(dir) || build.IsLocalImport(filepath.ToSlash(dir)) {
+		return dir
+	}
+	return "./" + dir
+}
+
+func warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+// configLoader removes files from the loaded packages following the project configuration:
+// excluded files, and test files and vendor directories unless the files are given explicitly.
+type configLoader struct {
+	scopelint.Loader
+}
+
+func (l *configLoader) Load(ctx context.Context, patterns ...string) ([]*scopelint.LoadedPackage, error) {
+	pkgs, err := l.Loader.Load(ctx, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range pkgs {
+		filterFiles(pkg)
+	}
+	return pkgs, nil
+}
+
+// Resolve resolves the packages with the loader, whose files are filtered when they are read.
+func (l *configLoader) Resolve(ctx context.Context, patterns ...string) ([]func() *scopelint.LoadedPackage, error) {
+	loads, err := scopelint.Resolve(ctx, l.Loader, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	for i, load := range loads {
+		load := load
+		loads[i] = func() *scopelint.LoadedPackage {
+			pkg := load()
+			filterFiles(pkg)
+			return pkg
+		}
+	}
+	return loads, nil
+}
+
+// filterFiles removes the files of the package following the project configuration.
+func filterFiles(pkg *scopelint.LoadedPackage) {
+	if pkg.Err != nil {
+		return
+	}
+	cfg, err := loadConfig(pkg.Dir)
+	if err != nil {
+		pkg.Err = err
+		return
+	}
+	explicit := pkg.Name == scopelint.CommandLineArguments
+	for filename := range pkg.Files {
+		fileCfg, err := loadConfig(filepath.Dir(filename))
+		if err != nil {
+			pkg.Err = err
+			return
+		}
+		switch {
+		case fileCfg.excluded(filename),
+			!explicit && !cfg.test() && strings.HasSuffix(filename, "_test.go"),
+			!explicit && !cfg.vendor() && strings.Contains(filename, "/vendor/"):
+			delete(pkg.Files, filename)
+		}
+	}
+}
+
+// eachPackage calls walk with each target package.
+// Packages which cannot be loaded are collected in runErrors.
+func eachPackage(walk func(pkg *scopelint.LoadedPackage)) {
+	if _, err := parseFailOn(params.failOn); err != nil {
+		fatal(err)
+	}
+	loader, patterns := targets()
+	pkgs, err := loader.Load(context.Background(), patterns...)
+	if err != nil {
+		fatal(err)
+	}
+	for _, pkg := range pkgs {
+		if pkg.Err != nil {
+			runErrors.add(newLintError(pkg.Name, pkg.Err, true))
+			continue
+		}
+		walk(pkg)
+	}
+}
@@ -2,31 +2,68 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"go/build"
-	"io/ioutil"
-	"log"
 	"os"
-	"path"
-	"path/filepath"
-	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/kyoh86/scopelint/scopelint"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
 
 var params struct {
-	argCount      int
-	arguments     arguments
-	setExitStatus bool
-	vendor        bool
-	test          bool
+	argCount           int
+	arguments          arguments
+	setExitStatus      bool
+	failOn             string
+	jobs               int
+	cacheDir           string
+	noCache            bool
+	shard              string
+	reports            []string
+	newFromPatch       string
+	newFromRev         string
+	vendor             bool
+	vendorSet          bool
+	test               bool
+	testSet            bool
+	showIgnored        bool
+	baseline           string
+	writeBaseline      string
+	format             string
+	formatSet          bool
+	templateFile       string
+	outputs            []string
+	color              string
+	context            int
+	contextSet         bool
+	suppressionsFormat string
+	configDir          string
+	workspace          string
+	tags               string
+	goos               string
+	goarch             string
+	allBuildContexts   bool
+	overlay            string
+	stdinFilename      string
 }
 
-var problems int
+// problems is the number of active problems found.
+var problems counter
+
+// counter is a count safe for concurrent use.
+type counter struct {
+	n int64
+}
+
+func (c *counter) add(n int) { atomic.AddInt64(&c.n, int64(n)) }
+
+func (c *counter) count() int { return int(atomic.LoadInt64(&c.n)) }
 
 var version = "snapshot"
 
@@ -35,398 +72,279 @@ func main() {
 	app.Author("kyoh86").Version(version)
 	app.VersionFlag.Short('v')
 
-	app.Flag("set-exit-status", "Set exit status to 1 if any problem variables are found").Default("true").BoolVar(&params.setExitStatus)
-	app.Flag("vendor", "Search lints in the `vendor` directories").Default("true").BoolVar(&params.vendor)
-	app.Flag("test", "Search lints in the `*_test.go` files").Default("true").BoolVar(&params.test)
-	arg := app.Arg("packages", "Set target packages")
+	app.Flag("vendor", "Search lints in the `vendor` directories").Default("true").Action(flagSet(&params.vendorSet)).BoolVar(&params.vendor)
+	app.Flag("test", "Search lints in the `*_test.go` files").Default("true").Action(flagSet(&params.testSet)).BoolVar(&params.test)
+
+	app.Flag("workspace", "Resolve packages in the modules of the go.work file (auto, off or the path of go.work)").Default(scopelint.WorkspaceAuto).StringVar(&params.workspace)
+
+	app.Flag("tags", "Set build tags separated by commas").PlaceHolder("TAG,...").StringVar(&params.tags)
+	app.Flag("goos", "Set the target operating system of the build context").PlaceHolder("GOOS").StringVar(&params.goos)
+	app.Flag("goarch", "Set the target architecture of the build context").PlaceHolder("GOARCH").StringVar(&params.goarch)
+	app.Flag("overlay", "Read files replaced in the JSON file of the same shape as the one of go build -overlay").PlaceHolder("FILE").ExistingFileVar(&params.overlay)
+	app.Flag("stdin-filename", "Set the path of the file whose source is read from stdin with the argument -").PlaceHolder("PATH").StringVar(&params.stdinFilename)
+	app.Flag("all-build-contexts", "Search lints in files for every combination of build constraints in each package").BoolVar(&params.allBuildContexts)
+
+	app.Flag("cache-dir", "Set the directory of the cache of problems (default: scopelint in the user cache directory like $XDG_CACHE_HOME)").PlaceHolder("DIR").StringVar(&params.cacheDir)
+
+	lintCmd := app.Command("lint", "Search lints in target packages").Default()
+	lintCmd.Flag("set-exit-status", "Set exit status to 1 if any problem variables are found").Default("true").BoolVar(&params.setExitStatus)
+	failOnFlag(lintCmd)
+	lintCmd.Flag("jobs", "Set the number of packages linted concurrently").Short('j').Default(strconv.Itoa(runtime.GOMAXPROCS(0))).PlaceHolder("N").IntVar(&params.jobs)
+	lintCmd.Flag("shard", "Lint only the packages in the shard INDEX of COUNT shards partitioned by a stable hash").PlaceHolder("INDEX/COUNT").StringVar(&params.shard)
+	lintCmd.Flag("no-cache", "Lint all packages without the cache").BoolVar(&params.noCache)
+	lintCmd.Flag("show-ignored", "Show problems ignored by directives and the number of them").BoolVar(&params.showIgnored)
+	lintCmd.Flag("format", "Set output format ("+strings.Join(formats, ", ")+" or "+templatePrefix+"TEXT)").Default(defaultFormat).Action(flagSet(&params.formatSet)).StringVar(&params.format)
+	lintCmd.Flag("output", "Write the report to the file instead of stdout, or another report like sarif=FILE,min-confidence=0.8,show-ignored (repeatable)").Short('o').PlaceHolder("[FORMAT=]FILE[,OPTION...]").StringsVar(&params.outputs)
+	lintCmd.Flag("color", "Colorize the output (auto, always or never)").Default(colorAuto).EnumVar(&params.color, colorAuto, colorAlways, colorNever)
+	lintCmd.Flag("context", "Show code frames with N lines around each problem (shown on terminals by default)").PlaceHolder("N").Action(flagSet(&params.contextSet)).IntVar(&params.context)
+	lintCmd.Flag("template-file", "Set output format to the template in the file").PlaceHolder("FILE").ExistingFileVar(&params.templateFile)
+	lintCmd.Flag("new-from-patch", "Report only problems on lines added or modified in the unified diff").PlaceHolder("FILE").ExistingFileVar(&params.newFromPatch)
+	lintCmd.Flag("new-from-rev", "Report only problems on lines added or modified since the git revision").PlaceHolder("REF").StringVar(&params.newFromRev)
+	lintCmd.Flag("baseline", "Report only problems not recorded in the baseline file").PlaceHolder("FILE").StringVar(&params.baseline)
+	lintCmd.Flag("write-baseline", "Record active problems to the baseline file").PlaceHolder("FILE").StringVar(&params.writeBaseline)
+	arg := lintCmd.Arg("packages", "Set target packages")
 	arg.CounterVar(&params.argCount)
 	arg.SetValue(&params.arguments)
-	kingpin.MustParse(app.Parse(os.Args[1:]))
 
-	for _, dir := range params.arguments.directories {
-		lintImportedPackage(build.ImportDir(dir, 0))
-	}
-	for _, pkgname := range importPaths(params.arguments.packages) {
-		lintImportedPackage(build.Import(pkgname, ".", 0))
-	}
-	lintFiles(params.arguments.files...)
+	suppressionsCmd := app.Command("suppressions", "List ignore directives in target packages")
+	suppressionsCmd.Flag("format", "Set output format").Default("table").EnumVar(&params.suppressionsFormat, "table", "json")
+	failOnFlag(suppressionsCmd)
+	suppressionsCmd.Arg("packages", "Set target packages").SetValue(&params.arguments)
 
-	if params.setExitStatus && problems > 0 {
-		fmt.Fprintf(os.Stderr, "Found %d lint problems; failing.\n", problems)
-		os.Exit(1)
-	}
-}
+	suppressCmd := app.Command("suppress", "Insert ignore directives for every active problem in target packages")
+	failOnFlag(suppressCmd)
+	suppressCmd.Arg("packages", "Set target packages").SetValue(&params.arguments)
 
-func lintFiles(filenames ...string) {
-	files := make(map[string][]byte)
-	for _, filename := range filenames {
-		src, err := ioutil.ReadFile(filename)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			continue
-		}
-		files[filename] = src
-	}
+	mergeCmd := app.Command("merge", "Merge JSON reports of shards into a report")
+	mergeCmd.Flag("set-exit-status", "Set exit status to 1 if any problem variables are found").Default("true").BoolVar(&params.setExitStatus)
+	failOnFlag(mergeCmd)
+	mergeCmd.Flag("show-ignored", "Show problems ignored by directives").BoolVar(&params.showIgnored)
+	mergeCmd.Flag("format", "Set output format ("+strings.Join(formats, ", ")+" or "+templatePrefix+"TEXT)").Default("json").StringVar(&params.format)
+	mergeCmd.Flag("output", "Write the report to the file instead of stdout, or another report like sarif=FILE (repeatable)").Short('o').PlaceHolder("[FORMAT=]FILE[,OPTION...]").StringsVar(&params.outputs)
+	mergeCmd.Flag("template-file", "Set output format to the template in the file").PlaceHolder("FILE").ExistingFileVar(&params.templateFile)
+	mergeCmd.Arg("reports", "Set JSON reports written by --format=json").Required().ExistingFilesVar(&params.reports)
 
-	l := new(scopelint.Linter)
-	ps, err := l.LintFiles(files)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		return
-	}
-	for _, p := range ps {
-		if p.Ignored {
-			continue
-		}
-		fmt.Printf("%v: %s\n", p.Position, p.Text)
-		problems++
-	}
-}
+	cacheCmd := app.Command("cache", "Manage the cache of problems")
+	cacheCleanCmd := cacheCmd.Command("clean", "Remove all entries in the cache")
 
-func lintImportedPackage(pkg *build.Package, err error) {
-	if err != nil {
-		if _, nogo := err.(*build.NoGoError); nogo {
-			// Don't complain if the failure is due to no Go source files.
-			return
-		}
-		fmt.Fprintln(os.Stderr, err)
-		return
-	}
+	configCmd := app.Command("config", "Manage the project configuration")
+	configPrintCmd := configCmd.Command("print", "Show the effective configuration for the directory and where each value came from")
+	configPrintCmd.Arg("dir", "Set the directory").Default(".").ExistingDirVar(&params.configDir)
 
-	var files []string
-	files = append(files, pkg.GoFiles...)
-	files = append(files, pkg.CgoFiles...)
-	if params.test {
-		files = append(files, pkg.TestGoFiles...)
-		files = append(files, pkg.XTestGoFiles...)
-	}
-	if pkg.Dir != "." {
-		for i, f := range files {
-			files[i] = filepath.Join(pkg.Dir, f)
+	command, err := app.Parse(stdinArguments(app.Model(), os.Args[1:]))
+	if err != nil {
+		app.Errorf("%s, try --help", err)
+		os.Exit(exitConfig)
+	}
+	setupBuildContext()
+	if err := setupOverlay(os.Stdin); err != nil {
+		fatal(err)
+	}
+	switch command {
+	case lintCmd.FullCommand():
+		lint()
+	case suppressionsCmd.FullCommand():
+		listSuppressions()
+	case suppressCmd.FullCommand():
+		suppress()
+	case mergeCmd.FullCommand():
+		merge()
+	case cacheCleanCmd.FullCommand():
+		if err := cleanCache(); err != nil {
+			fatal(err)
 		}
-	}
-	if !params.vendor {
-		var target []string
-		for _, f := range files {
-			if strings.Contains(f, "/vendor/") {
-				continue
-			}
-			target = append(target, f)
+	case configPrintCmd.FullCommand():
+		if err := printConfig(os.Stdout, params.configDir); err != nil {
+			fatal(err)
 		}
-		files = target
 	}
-
-	lintFiles(files...)
 }
 
-/*
-
-This file holds a direct copy of the import path matching code of
-https://github.com/golang/go/blob/master/src/cmd/go/main.go. It can be
-replaced when https://golang.org/issue/8768 is resolved.
-
-It has been updated to follow upstream changes in a few ways.
-
-*/
-
-var buildContext = build.Default
-
-var (
-	goroot    = filepath.Clean(runtime.GOROOT())
-	gorootSrc = filepath.Join(goroot, "src")
-)
-
-// importPathsNoDotExpansion returns the import paths to use for the given
-// command line, but it does no ... expansion.
-func importPathsNoDotExpansion(args []string) []string {
-	if len(args) == 0 {
-		return nil
-	}
-	var out []string
-	for _, a := range args {
-		// Arguments are supposed to be import paths, but
-		// as a courtesy to Windows developers, rewrite \ to /
-		// in command-line arguments.  Handles .\... and so on.
-		if filepath.Separator == '\\' {
-			a = strings.Replace(a, `\`, `/`, -1)
-		}
-
-		// Put argument in canonical form, but preserve leading ./.
-		if strings.HasPrefix(a, "./") {
-			a = "./" + path.Clean(a)
-			if a == "./." {
-				a = "."
+// stdinArguments moves the argument "-" (to read the source from stdin) after "--",
+// since kingpin takes it as a flag.
+func stdinArguments(model *kingpin.ApplicationModel, args []string) []string {
+	valueFlags := map[string]bool{}
+	addFlags := func(flags []*kingpin.FlagModel) {
+		for _, f := range flags {
+			if !f.IsBoolFlag() {
+				valueFlags["--"+f.Name] = true
+				if f.Short != 0 {
+					valueFlags["-"+string(f.Short)] = true
+				}
 			}
-		} else {
-			a = path.Clean(a)
 		}
-		if a == allPackage || a == standardPackages {
-			out = append(out, allPackages(a)...)
-			continue
+	}
+	addFlags(model.Flags)
+	var addCommands func(commands []*kingpin.CmdModel)
+	addCommands = func(commands []*kingpin.CmdModel) {
+		for _, c := range commands {
+			addFlags(c.Flags)
+			addCommands(c.Commands)
 		}
-		out = append(out, a)
 	}
-	return out
-}
+	addCommands(model.Commands)
 
-// importPaths returns the import paths to use for the given command line.
-func importPaths(args []string) []string {
-	args = importPathsNoDotExpansion(args)
-	var out []string
-	for _, a := range args {
-		if strings.Contains(a, "...") {
-			if build.IsLocalImport(a) {
-				out = append(out, allPackagesInFS(a)...)
-			} else {
-				out = append(out, allPackages(a)...)
+	for i, arg := range args {
+		if arg == "--" {
+			return args
+		}
+		if arg == stdinArgument && (i == 0 || !valueFlags[args[i-1]]) {
+			moved := append(append([]string{}, args[:i]...), args[i+1:]...)
+			for j, a := range moved {
+				if a == "--" {
+					return append(moved[:j+1], append([]string{stdinArgument}, moved[j+1:]...)...)
+				}
 			}
-			continue
+			return append(moved, "--", stdinArgument)
 		}
-		out = append(out, a)
 	}
-	return out
+	return args
 }
 
-// matchPattern(pattern)(name) reports whether
-// name matches pattern.  Pattern is a limited glob
-// pattern in which '...' means 'any string' and there
-// is no other special syntax.
-func matchPattern(pattern string) func(name string) bool {
-	re := regexp.QuoteMeta(pattern)
-	re = strings.Replace(re, `\.\.\.`, `.*`, -1)
-	// Special case: foo/... matches foo too.
-	if strings.HasSuffix(re, `/.*`) {
-		re = re[:len(re)-len(`/.*`)] + `(/.*)?`
-	}
-	reg := regexp.MustCompile(`^` + re + `$`)
-	return func(name string) bool {
-		return reg.MatchString(name)
-	}
+// failOnFlag defines the --fail-on flag of the command.
+func failOnFlag(cmd *kingpin.CmdClause) {
+	cmd.Flag("fail-on", "Set the classes of failures which fail the run (findings, load, parse, config, errors, all or none) separated by commas").Default("all").PlaceHolder("CLASS,...").StringVar(&params.failOn)
 }
 
-// hasPathPrefix reports whether the path s begins with the
-// elements in prefix.
-func hasPathPrefix(s, prefix string) bool {
-	switch {
-	default:
-		return false
-	case len(s) == len(prefix):
-		return s == prefix
-	case len(s) > len(prefix):
-		if prefix != "" && prefix[len(prefix)-1] == '/' {
-			return strings.HasPrefix(s, prefix)
-		}
-		return s[len(prefix)] == '/' && s[:len(prefix)] == prefix
+// flagSet returns an action to mark that the flag is set by the user.
+func flagSet(set *bool) kingpin.Action {
+	return func(*kingpin.ParseContext) error {
+		*set = true
+		return nil
 	}
 }
 
-// treeCanMatchPattern(pattern)(name) reports whether
-// name or children of name can possibly match pattern.
-// Pattern is the same limited glob accepted by matchPattern.
-func treeCanMatchPattern(pattern string) func(name string) bool {
-	wildCard := false
-	if i := strings.Index(pattern, "..."); i >= 0 {
-		wildCard = true
-		pattern = pattern[:i]
+var (
+	output       reporter
+	base         *baseline
+	baseRecorder *baselineWriter
+	newChanges   changes // lines changed in the diff of --new-from-patch or --new-from-rev
+)
+
+func lint() {
+	if _, err := parseFailOn(params.failOn); err != nil {
+		fatal(err)
 	}
-	return func(name string) bool {
-		return len(name) <= len(pattern) && hasPathPrefix(pattern, name) ||
-			wildCard && strings.HasPrefix(name, pattern)
+	if params.baseline != "" {
+		b, err := readBaseline(params.baseline)
+		if err != nil {
+			fatal(err)
+		}
+		base = b
 	}
-}
-
-// allPackages returns all the packages that can be found
-// under the $GOPATH directories and $GOROOT matching pattern.
-// The pattern is either "all" (all packages), "std" (standard packages)
-// or a path including "...".
-func allPackages(pattern string) []string {
-	pkgs := matchPackages(pattern)
-	if len(pkgs) == 0 {
-		fmt.Fprintf(os.Stderr, "warning: %q matched no packages\n", pattern)
+	if params.writeBaseline != "" {
+		baseRecorder = &baselineWriter{}
 	}
-	return pkgs
-}
 
-const (
-	standardPackages = "std"
-	commandPackages  = "cmd"
-	allPackage       = "all"
-)
-
-func matchPackages(pattern string) []string {
-	match := func(string) bool { return true }
-	treeCanMatch := func(string) bool { return true }
-	if pattern != allPackage && pattern != standardPackages {
-		match = matchPattern(pattern)
-		treeCanMatch = treeCanMatchPattern(pattern)
+	c, err := loadChanges()
+	if err != nil {
+		fatal(err)
 	}
+	newChanges = c
 
-	have := map[string]bool{
-		"builtin": true, // ignore pseudo-package that exists only for documentation
+	cfg, err := loadConfig(".")
+	if err != nil {
+		fatal(err)
 	}
-	if !buildContext.CgoEnabled {
-		have["runtime/cgo"] = true // ignore during walk
+	output, err = openOutputs(cfg.format(), params.outputs)
+	if err != nil {
+		fatal(err)
 	}
-	var pkgs []string
-
-	// Commands
-	cmd := filepath.Join(goroot, "src/cmd") + string(filepath.Separator)
-	if err := filepath.Walk(cmd, func(path string, fi os.FileInfo, err error) error {
-		if err != nil || !fi.IsDir() || path == cmd {
-			return nil
-		}
-		name := path[len(cmd):]
-		if !treeCanMatch(name) {
-			return filepath.SkipDir
-		}
-		// Commands are all in cmd/, not in subdirectories.
-		if strings.Contains(name, string(filepath.Separator)) {
-			return filepath.SkipDir
-		}
-
-		// We use, e.g., cmd/gofmt as the pseudo import path for gofmt.
-		name = "cmd/" + name
-		if have[name] {
-			return nil
-		}
-		have[name] = true
-		if !match(name) {
-			return nil
-		}
-		_, err = buildContext.ImportDir(path, 0)
+	loader, patterns := targets()
+	if params.shard != "" {
+		shard, err := scopelint.ParseShard(params.shard)
 		if err != nil {
-			if _, noGo := err.(*build.NoGoError); !noGo {
-				log.Print(err)
-			}
-			return nil
+			fatal(&configError{err})
+		}
+		if patterns, err = shardPatterns(loader, patterns, shard); err != nil {
+			fatal(err)
 		}
-		pkgs = append(pkgs, name)
+	}
+	linter := &scopelint.Linter{Configure: configure, Jobs: params.jobs, Cache: newCache()}
+	if err := linter.LintPatternsFunc(context.Background(), loader, func(result scopelint.Result) error {
+		reportPackage(result)
 		return nil
-	}); err != nil {
-		panic(err)
+	}, patterns...); err != nil {
+		fatal(err)
+	}
+	if err := runErrors.report(output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if err := output.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 	}
 
-	for _, src := range buildContext.SrcDirs() {
-		if (pattern == standardPackages || pattern == commandPackages) && src != gorootSrc {
-			continue
+	if base != nil {
+		for _, f := range base.resolved() {
+			fmt.Fprintf(os.Stderr, "%s: %s: resolved since the baseline (%d): %s\n", f.Filename, f.Function, f.Count, f.Text)
 		}
-		src = filepath.Clean(src) + string(filepath.Separator)
-		root := src
-		if pattern == commandPackages {
-			root += commandPackages + string(filepath.Separator)
-		}
-		if err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
-			if err != nil || !fi.IsDir() || path == src {
-				return nil
-			}
-
-			// Avoid .foo, _foo, and testdata directory trees.
-			_, elem := filepath.Split(path)
-			if strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") || elem == "testdata" {
-				return filepath.SkipDir
-			}
-
-			name := filepath.ToSlash(path[len(src):])
-			if pattern == standardPackages && (strings.Contains(name, ".") || name == commandPackages) {
-				// The name "std" is only the standard library.
-				// If the name is cmd, it's the root of the command tree.
-				return filepath.SkipDir
-			}
-			if !treeCanMatch(name) {
-				return filepath.SkipDir
-			}
-			if have[name] {
-				return nil
-			}
-			have[name] = true
-			if !match(name) {
-				return nil
-			}
-			_, err = buildContext.ImportDir(path, 0)
-			if _, noGo := err.(*build.NoGoError); noGo {
-				return nil
-			}
-			pkgs = append(pkgs, name)
-			return nil
-		}); err != nil {
-			panic(err)
+	}
+	if baseRecorder != nil {
+		if err := baseRecorder.write(params.writeBaseline); err != nil {
+			fatal(err)
 		}
+		// The recorded problems do not fail the run.
+		finish(0, runErrors.list())
 	}
-	return pkgs
+	finish(problems.count(), runErrors.list())
 }
 
-// allPackagesInFS is like allPackages but is passed a pattern
-// beginning ./ or ../, meaning it should scan the tree rooted
-// at the given directory.  There are ... in the pattern too.
-func allPackagesInFS(pattern string) []string {
-	pkgs := matchPackagesInFS(pattern)
-	if len(pkgs) == 0 {
-		fmt.Fprintf(os.Stderr, "warning: %q matched no packages\n", pattern)
+// configure returns the linter for the package following the project configuration.
+func configure(pkg *scopelint.LoadedPackage) *scopelint.Linter {
+	cfg, err := loadConfig(pkg.Dir)
+	if err != nil {
+		return &scopelint.Linter{GoVersion: pkg.Build.GoVersion}
 	}
-	return pkgs
+	return cfg.linter(pkg)
 }
 
-func matchPackagesInFS(pattern string) []string {
-	// Find directory to begin the scan.
-	// Could be smarter but this one optimization
-	// is enough for now, since ... is usually at the
-	// end of a path.
-	i := strings.Index(pattern, "...")
-	dir, _ := path.Split(pattern[:i])
-
-	// pattern begins with ./ or ../.
-	// path.Clean will discard the ./ but not the ../.
-	// We need to preserve the ./ for pattern matching
-	// and in the returned import paths.
-	prefix := ""
-	if strings.HasPrefix(pattern, "./") {
-		prefix = "./"
-	}
-	match := matchPattern(pattern)
-
-	var pkgs []string
-	if err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
-		if err != nil || !fi.IsDir() {
-			return nil
-		}
-		if path == dir {
-			// filepath.Walk starts at dir and recurses. For the recursive case,
-			// the path is the result of filepath.Join, which calls filepath.Clean.
-			// The initial case is not Cleaned, though, so we do this explicitly.
-			//
-			// This converts a path like "./io/" to "io". Without this step, running
-			// "cd $GOROOT/src/pkg; go list ./io/..." would incorrectly skip the io
-			// package, because prepending the prefix "./" to the unclean path would
-			// result in "././io", and match("././io") returns false.
-			path = filepath.Clean(path)
-		}
+func reportPackage(result scopelint.Result) {
+	pkg := result.Package
+	if result.Err != nil {
+		runErrors.add(newLintError(pkg.Name, result.Err, pkg.Err != nil))
+		return
+	}
+	cfg, err := loadConfig(pkg.Dir)
+	if err != nil {
+		runErrors.add(newLintError(pkg.Name, err, true))
+		return
+	}
 
-		// Avoid .foo, _foo, and testdata directory trees, but do not avoid "." or "..".
-		_, elem := filepath.Split(path)
-		dot := strings.HasPrefix(elem, ".") && elem != "." && elem != ".."
-		if dot || strings.HasPrefix(elem, "_") || elem == "testdata" {
-			return filepath.SkipDir
-		}
+	ps := cfg.filter(result.Problems)
+	if baseRecorder != nil {
+		baseRecorder.record(ps)
+	}
+	if base != nil {
+		ps = base.filter(pkg.Filenames(), ps)
+	}
+	if newChanges != nil {
+		ps = newChanges.filter(ps)
+	}
+	if params.arguments.stdin {
+		ps = stdinProblems(ps)
+	}
 
-		name := prefix + filepath.ToSlash(path)
-		if !match(name) {
-			return nil
-		}
-		if _, err = build.ImportDir(path, 0); err != nil {
-			if _, noGo := err.(*build.NoGoError); !noGo {
-				log.Print(err)
-			}
-			return nil
+	var active, ignored int
+	for _, p := range ps {
+		if p.Ignored {
+			ignored++
+			continue
 		}
-		pkgs = append(pkgs, name)
-		return nil
-	}); err != nil {
-		panic(err)
+		active++
+	}
+	problems.add(active)
+	if err := output.Report(pkg.Name, ps); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if params.showIgnored && len(ps) > 0 {
+		fmt.Fprintf(os.Stderr, "%s: %d active, %d ignored\n", pkg.Name, active, ignored)
 	}
-	return pkgs
 }
 
+var buildContext = build.Default
+
 type arguments struct {
+	stdin       bool // the package of the file given by --stdin-filename, with the source from stdin
 	packages    []string
 	files       []string
 	directories []string
@@ -447,8 +365,12 @@ func isFileExists(filename string) bool {
 }
 
 func (a *arguments) Set(arg string) error {
+	if arg == stdinArgument {
+		return a.addStdin()
+	}
 	if strings.HasSuffix(arg, "/...") && isDirExists(arg[:len(arg)-len("/...")]) {
-		return a.addDirectory(allPackagesInFS(arg)...)
+		// Expanded on walking, after all flags are parsed.
+		return a.addDirectory(arg)
 	}
 	if isDirExists(arg) {
 		return a.addDirectory(arg)
@@ -460,24 +382,32 @@ func (a *arguments) Set(arg string) error {
 }
 
 func (a *arguments) addDirectory(directory ...string) error {
-	if len(a.packages) > 0 || len(a.files) > 0 {
-		return errors.New("cannot search lints in directories with packages or files")
+	if a.stdin || len(a.packages) > 0 || len(a.files) > 0 {
+		return errors.New("cannot search lints in directories with packages, files or stdin")
 	}
 	a.directories = append(a.directories, directory...)
 	return nil
 }
 
+func (a *arguments) addStdin() error {
+	if len(a.packages) > 0 || len(a.files) > 0 || len(a.directories) > 0 {
+		return errors.New("cannot search lints in stdin with packages, files or directories")
+	}
+	a.stdin = true
+	return nil
+}
+
 func (a *arguments) addFile(file string) error {
-	if len(a.packages) > 0 || len(a.directories) > 0 {
-		return errors.New("cannot search lints in files with packages or directories")
+	if a.stdin || len(a.packages) > 0 || len(a.directories) > 0 {
+		return errors.New("cannot search lints in files with packages, directories or stdin")
 	}
 	a.files = append(a.files, file)
 	return nil
 }
 
 func (a *arguments) addPackage(p string) error {
-	if len(a.files) > 0 || len(a.directories) > 0 {
-		return errors.New("cannot search lints in packages with files or directories")
+	if a.stdin || len(a.files) > 0 || len(a.directories) > 0 {
+		return errors.New("cannot search lints in packages with files, directories or stdin")
 	}
 	a.packages = append(a.packages, p)
 	return nil
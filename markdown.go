@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// markdownLimit is the size of a markdown report over which the details of files are omitted.
+// It is a little less than the limit of a comment on GitHub (65536 characters).
+const markdownLimit = 60000
+
+// markdownRowReserve is the size reserved in the limit for the row of the number of packages omitted from the table.
+const markdownRowReserve = 64
+
+// markdownReporter reports problems in a markdown document for pull request comments:
+// a summary table of problems per package and category, and collapsible details of each file.
+// All problems are buffered and written on Close.
+type markdownReporter struct {
+	w           io.Writer
+	showIgnored bool
+	limit       int
+	counts      []markdownCount
+	problems    []scopelint.Problem
+	errors      []lintError
+	sources     sourceCache
+}
+
+type markdownCount struct {
+	pkg      string
+	category string
+	active   int
+	ignored  int
+}
+
+func newMarkdownReporter(w io.Writer, showIgnored bool) *markdownReporter {
+	return &markdownReporter{
+		w:           w,
+		showIgnored: showIgnored,
+		limit:       markdownLimit,
+		sources:     sourceCache{},
+	}
+}
+
+func (r *markdownReporter) Report(pkg string, problems []scopelint.Problem) error {
+	counts := map[string]*markdownCount{}
+	var categories []string
+	for _, p := range problems {
+		count, ok := counts[p.Category]
+		if !ok {
+			count = &markdownCount{pkg: pkg, category: p.Category}
+			counts[p.Category] = count
+			categories = append(categories, p.Category)
+		}
+		if p.Ignored {
+			count.ignored++
+			if !r.showIgnored {
+				continue
+			}
+		} else {
+			count.active++
+		}
+		r.problems = append(r.problems, p)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		r.counts = append(r.counts, *counts[category])
+	}
+	return nil
+}
+
+func (r *markdownReporter) ReportError(e lintError) error {
+	r.errors = append(r.errors, e)
+	return nil
+}
+
+func (r *markdownReporter) Close() error {
+	var b strings.Builder
+	b.WriteString("## scopelint\n\n")
+	r.writeErrors(&b)
+	var active, ignored int
+	for _, count := range r.counts {
+		active += count.active
+		ignored += count.ignored
+	}
+	if len(r.counts) == 0 {
+		if len(r.errors) == 0 {
+			b.WriteString("No problems found.\n")
+		}
+		_, err := io.WriteString(r.w, b.String())
+		return err
+	}
+	fmt.Fprintf(&b, "Found %s (%d ignored).\n\n", plural(active, "problem"), ignored)
+	b.WriteString("| Package | Category | Active | Ignored |\n")
+	b.WriteString("| --- | --- | ---: | ---: |\n")
+	for i, count := range r.counts {
+		row := fmt.Sprintf("| `%s` | %s | %d | %d |\n", markdownCell(count.pkg), markdownCell(count.category), count.active, count.ignored)
+		if b.Len()+len(row)+markdownRowReserve > r.limit {
+			fmt.Fprintf(&b, "| … and %s | | | |\n", plural(countPackages(r.counts[i:]), "more package"))
+			break
+		}
+		b.WriteString(row)
+	}
+
+	scopelint.SortProblems(r.problems)
+	var omittedFiles, omittedProblems int
+	for start := 0; start < len(r.problems); {
+		end := start + 1
+		for end < len(r.problems) && r.problems[end].Position.Filename == r.problems[start].Position.Filename {
+			end++
+		}
+		section := r.fileSection(r.problems[start:end])
+		if omittedFiles > 0 || b.Len()+len(section) > r.limit {
+			omittedFiles++
+			omittedProblems += end - start
+		} else {
+			b.WriteString(section)
+		}
+		start = end
+	}
+	if omittedFiles > 0 {
+		fmt.Fprintf(&b, "\n_%s in %s are omitted._\n", plural(omittedProblems, "problem"), plural(omittedFiles, "file"))
+	}
+	_, err := io.WriteString(r.w, b.String())
+	return err
+}
+
+// writeErrors writes the list of the errors in the run, which is truncated in the limit.
+func (r *markdownReporter) writeErrors(b *strings.Builder) {
+	if len(r.errors) == 0 {
+		return
+	}
+	errs := append([]lintError(nil), r.errors...)
+	sortErrors(errs)
+	fmt.Fprintf(b, "Found %s.\n\n", errorSummary(errs))
+	for i, e := range errs {
+		item := fmt.Sprintf("- **%s**", e.Class)
+		if e.Package != "" {
+			item += fmt.Sprintf(" `%s`", e.Package)
+		}
+		item += ": " + strings.Join(strings.Fields(e.Message), " ") + "\n"
+		if b.Len()+len(item)+markdownRowReserve > r.limit {
+			fmt.Fprintf(b, "- … and %s\n", plural(len(errs)-i, "more error"))
+			break
+		}
+		b.WriteString(item)
+	}
+	b.WriteString("\n")
+}
+
+// countPackages returns the number of packages in the counts, which are grouped by package.
+func countPackages(counts []markdownCount) int {
+	n := 0
+	for i, count := range counts {
+		if i == 0 || count.pkg != counts[i-1].pkg {
+			n++
+		}
+	}
+	return n
+}
+
+// fileSection returns a collapsible section for the problems in a file.
+func (r *markdownReporter) fileSection(problems []scopelint.Problem) string {
+	var b strings.Builder
+	filename, _ := relativePath(problems[0].Position.Filename)
+	fmt.Fprintf(&b, "\n<details>\n<summary><code>%s</code> (%s)</summary>\n", htmlEscaper.Replace(filename), plural(len(problems), "problem"))
+	for _, p := range problems {
+		fmt.Fprintf(&b, "\n**Line %d, column %d** `%s`: %s", p.Position.Line, p.Position.Column, p.Category, p.Text)
+		if p.Ignored {
+			b.WriteString(" (ignored)")
+		}
+		b.WriteString("\n")
+		if p.LineText != "" {
+			fmt.Fprintf(&b, "\n```go\n%s\n```\n", strings.TrimSpace(p.LineText))
+		}
+		if p.Ignored {
+			continue
+		}
+		for _, fix := range p.Fixes {
+			fmt.Fprintf(&b, "\n%s:\n\n```diff\n", fix.Message)
+			for _, edit := range fix.Edits {
+				for _, line := range editDiff(r.sources.lines(edit.Position.Filename), edit) {
+					b.WriteString(line + "\n")
+				}
+			}
+			b.WriteString("```\n")
+		}
+	}
+	b.WriteString("\n</details>\n")
+	return b.String()
+}
+
+// editDiff returns the lines of a unified diff (without headers) for the edit of the source lines.
+// If the source is not available, it returns the inserted text as added lines.
+func editDiff(lines []string, edit scopelint.Edit) []string {
+	start, end := edit.Position.Line, edit.End.Line
+	if start < 1 || end < start || end > len(lines) {
+		var diff []string
+		for _, line := range strings.Split(strings.Trim(edit.NewText, "\n"), "\n") {
+			diff = append(diff, "+"+line)
+		}
+		return diff
+	}
+	oldLines := lines[start-1 : end]
+	old := strings.Join(oldLines, "\n")
+	endOffset := len(old) - len(oldLines[len(oldLines)-1]) + edit.End.Column - 1
+	startOffset := edit.Position.Column - 1
+	if endOffset > len(old) {
+		endOffset = len(old)
+	}
+	if startOffset > endOffset {
+		startOffset = endOffset
+	}
+	newLines := strings.Split(old[:startOffset]+edit.NewText+old[endOffset:], "\n")
+
+	// Strip common lines as the context.
+	var prefix, suffix int
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	var diff []string
+	for _, line := range oldLines[:prefix] {
+		diff = append(diff, " "+line)
+	}
+	for _, line := range oldLines[prefix : len(oldLines)-suffix] {
+		diff = append(diff, "-"+line)
+	}
+	for _, line := range newLines[prefix : len(newLines)-suffix] {
+		diff = append(diff, "+"+line)
+	}
+	for _, line := range oldLines[len(oldLines)-suffix:] {
+		diff = append(diff, " "+line)
+	}
+	return diff
+}
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&#34;", "'", "&#39;")
+
+// markdownCell escapes a text in a cell of a markdown table.
+func markdownCell(text string) string {
+	return strings.Replace(text, "|", `\|`, -1)
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
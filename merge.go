@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// merge merges the JSON reports of shards given in the arguments into a report.
+// The exit status follows the problems and the errors in the reports.
+func merge() {
+	if _, err := parseFailOn(params.failOn); err != nil {
+		fatal(err)
+	}
+	output, err := openOutputs(params.format, params.outputs)
+	if err != nil {
+		fatal(err)
+	}
+	active, errs, err := mergeReports(params.reports, output)
+	if cerr := output.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fatal(err)
+	}
+	finish(active, errs)
+}
+
+// mergeReports reports the packages and the errors in the JSON reports to the reporter,
+// and returns the number of active problems and the errors.
+// Packages are sorted by name and problems by position, and problems and errors found in several reports
+// are reported once, so the result does not depend on the order of the reports or how packages are partitioned into them.
+func mergeReports(filenames []string, r reporter) (int, []lintError, error) {
+	packages := map[string][]scopelint.Problem{}
+	seen := map[string]bool{}
+	var errs []lintError
+	seenErrors := map[lintError]bool{}
+	for _, filename := range filenames {
+		report, err := readReport(filename)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, e := range report.Errors {
+			if !seenErrors[e] {
+				seenErrors[e] = true
+				errs = append(errs, e)
+			}
+		}
+		for _, pkg := range report.Packages {
+			problems := packages[pkg.Name]
+			for _, p := range pkg.Problems {
+				raw, err := json.Marshal(p)
+				if err != nil {
+					return 0, nil, err
+				}
+				key := pkg.Name + "\x00" + string(raw)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				problems = append(problems, p)
+			}
+			packages[pkg.Name] = problems
+		}
+	}
+
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var active int
+	for _, name := range names {
+		problems := packages[name]
+		scopelint.SortProblems(problems)
+		for _, p := range problems {
+			if !p.Ignored {
+				active++
+			}
+		}
+		if err := r.Report(name, problems); err != nil {
+			return 0, nil, err
+		}
+	}
+	sortErrors(errs)
+	for _, e := range errs {
+		if err := reportError(r, e); err != nil {
+			return 0, nil, err
+		}
+	}
+	return active, errs, nil
+}
+
+// readReport reads a report written in the json format.
+func readReport(filename string) (*jsonReport, error) {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var report jsonReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return nil, fmt.Errorf("invalid report %s: %v", filename, err)
+	}
+	if report.Version != scopelint.SchemaVersion {
+		return nil, fmt.Errorf("unsupported report version %d in %s", report.Version, filename)
+	}
+	return &report, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kyoh86/scopelint/scopelint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeReports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scopelint-merge")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	problem := func(filename string, line int, ignored bool) scopelint.Problem {
+		return scopelint.Problem{
+			Position: token.Position{Filename: filename, Line: line, Column: 1},
+			Text:     "Using the variable on range scope \"v\" in function literal",
+			Ignored:  ignored,
+		}
+	}
+	loadError := lintError{Class: classLoad, Package: "d", Message: "cannot find package \"d\""}
+	write := func(name string, packages map[string][]scopelint.Problem, order ...string) string {
+		var buf bytes.Buffer
+		r := &jsonReporter{w: &buf}
+		for _, pkg := range order {
+			require.NoError(t, r.Report(pkg, packages[pkg]))
+		}
+		require.NoError(t, r.ReportError(loadError))
+		require.NoError(t, r.Close())
+		filename := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(filename, buf.Bytes(), 0644))
+		return filename
+	}
+	shard1 := write("1.json", map[string][]scopelint.Problem{
+		"b": {problem("b/b.go", 9, false), problem("b/b.go", 3, true)},
+		"c": nil,
+	}, "c", "b")
+	shard2 := write("2.json", map[string][]scopelint.Problem{
+		"a": {problem("a/a.go", 5, false)},
+		"b": {problem("b/b.go", 9, false)},
+	}, "b", "a")
+
+	var buf bytes.Buffer
+	r := &jsonReporter{w: &buf}
+	active, errs, err := mergeReports([]string{shard2, shard1}, r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, 2, active)
+	assert.Equal(t, []lintError{loadError}, errs, "duplicated errors are removed")
+
+	var merged jsonReport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &merged))
+	assert.Equal(t, jsonTotals{Packages: 3, Active: 2, Ignored: 1, Errors: 1}, merged.Totals)
+	assert.Equal(t, []lintError{loadError}, merged.Errors)
+	var names []string
+	for _, pkg := range merged.Packages {
+		names = append(names, pkg.Name)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, names, "packages are sorted")
+	require.Len(t, merged.Packages[1].Problems, 2, "duplicates are removed")
+	assert.Equal(t, 3, merged.Packages[1].Problems[0].Position.Line, "problems are sorted")
+	assert.Equal(t, 9, merged.Packages[1].Problems[1].Position.Line)
+
+	invalid := filepath.Join(dir, "invalid.json")
+	require.NoError(t, ioutil.WriteFile(invalid, []byte(`{"version":0}`), 0644))
+	_, _, err = mergeReports([]string{shard1, invalid}, &jsonReporter{w: ioutil.Discard})
+	assert.Error(t, err)
+}
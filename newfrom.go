@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// changes are the lines added or modified in a diff, by absolute filenames.
+type changes map[string]*fileChanges
+
+type fileChanges struct {
+	all   bool // the whole file is new
+	lines map[int]bool
+}
+
+// loadChanges returns the changes given by the --new-from-patch or --new-from-rev flag,
+// or nil if they are not given.
+func loadChanges() (changes, error) {
+	switch {
+	case params.newFromPatch != "" && params.newFromRev != "":
+		return nil, &configError{fmt.Errorf("cannot use --new-from-patch with --new-from-rev")}
+	case params.newFromPatch != "":
+		file, err := os.Open(params.newFromPatch)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		c, err := parseDiff(file, patchFilename("."))
+		if err != nil {
+			return nil, fmt.Errorf("invalid patch %s: %v", params.newFromPatch, err)
+		}
+		return c, nil
+	case params.newFromRev != "":
+		return revisionChanges(".", params.newFromRev)
+	}
+	return nil, nil
+}
+
+// patchFilename returns a function which returns the absolute path of a file in a patch.
+// Paths are relative to the directory, or to the top level of the git repository containing it
+// (like `git diff` writes them) if the file is not found in the directory.
+// The prefix "b/" of git is removed unless the file exists with it.
+func patchFilename(dir string) func(name string) string {
+	var root *string // resolved on the first relative path
+	return func(name string) string {
+		if filepath.IsAbs(name) {
+			return name
+		}
+		names := []string{filepath.FromSlash(name)}
+		if strings.HasPrefix(name, "b/") {
+			names = append(names, filepath.FromSlash(name[len("b/"):]))
+		}
+		if root == nil {
+			top, _ := gitTopLevel(dir)
+			root = &top
+		}
+		bases := []string{dir}
+		if *root != "" {
+			bases = append(bases, *root)
+		}
+		for _, base := range bases {
+			for _, n := range names {
+				if filename := filepath.Join(base, n); isFileExists(filename) {
+					return absPath(filename)
+				}
+			}
+		}
+		return absPath(filepath.Join(dir, names[len(names)-1]))
+	}
+}
+
+// absPath returns the absolute path of the file, or the file itself if it cannot be resolved.
+func absPath(filename string) string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return filename
+	}
+	return abs
+}
+
+// gitTopLevel returns the absolute path of the top level of the git repository containing the directory.
+func gitTopLevel(dir string) (string, error) {
+	// --show-cdup keeps symbolic links in the path of the working directory, unlike --show-toplevel.
+	cdup, err := git(dir, "rev-parse", "--show-cdup")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(filepath.Join(dir, strings.TrimSpace(string(cdup))))
+}
+
+// revisionChanges returns the changes in the working tree of the git repository containing the directory
+// from the revision, including untracked files.
+func revisionChanges(dir, rev string) (changes, error) {
+	root, err := gitTopLevel(dir)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := git(root, "diff", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/", "-U0", rev, "--")
+	if err != nil {
+		return nil, err
+	}
+	c, err := parseDiff(bytes.NewReader(diff), func(name string) string {
+		return filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(name, "b/")))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid output of git diff: %v", err)
+	}
+	untracked, err := git(root, "ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(string(untracked), "\x00") {
+		if name != "" {
+			c[filepath.Join(root, filepath.FromSlash(name))] = &fileChanges{all: true}
+		}
+	}
+	return c, nil
+}
+
+// git runs the git command in the directory and returns its output.
+func git(dir string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return out, nil
+}
+
+// parseDiff reads the lines added or modified in a unified diff.
+// The names of the new files are converted to absolute paths by filename.
+func parseDiff(r io.Reader, filename func(name string) string) (changes, error) {
+	c := changes{}
+	var file *fileChanges
+	var next, oldRemaining, newRemaining int // the line number in the new file, and the numbers of lines left in the hunk
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if oldRemaining > 0 || newRemaining > 0 {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				if file != nil {
+					file.lines[next] = true
+				}
+				next++
+				newRemaining--
+			case strings.HasPrefix(line, "-"):
+				oldRemaining--
+			case strings.HasPrefix(line, " "), line == "":
+				next++
+				oldRemaining--
+				newRemaining--
+			case strings.HasPrefix(line, `\`):
+				// \ No newline at end of file
+			default:
+				return nil, fmt.Errorf("line %d: unexpected line in a hunk", n)
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			name, err := diffFilename(line[len("+++ "):])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			if name == "/dev/null" {
+				file = nil
+				continue
+			}
+			abs := filename(name)
+			if file = c[abs]; file == nil {
+				file = &fileChanges{lines: map[int]bool{}}
+				c[abs] = file
+			}
+		case strings.HasPrefix(line, "@@ "):
+			// file is nil for a deleted file.
+			var err error
+			if next, oldRemaining, newRemaining, err = parseHunkHeader(line); err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+		}
+	}
+	return c, scanner.Err()
+}
+
+// diffFilename returns the filename in a header line of a diff, which may be quoted and followed by a timestamp.
+func diffFilename(s string) (string, error) {
+	if strings.HasPrefix(s, `"`) {
+		end := strings.LastIndex(s, `"`)
+		return strconv.Unquote(s[:end+1])
+	}
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// parseHunkHeader parses a line like `@@ -1,2 +3,4 @@` into the first line number in the new file
+// and the numbers of the lines in the old and the new file.
+func parseHunkHeader(line string) (start, oldLines, newLines int, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[3] != "@@" || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q", line)
+	}
+	_, oldLines, err = parseRange(fields[1][1:])
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	start, newLines, err = parseRange(fields[2][1:])
+	return start, oldLines, newLines, err
+}
+
+// parseRange parses a range like `3,4` or `3` in a hunk header.
+func parseRange(s string) (start, lines int, err error) {
+	lines = 1
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		if lines, err = strconv.Atoi(s[i+1:]); err != nil {
+			return 0, 0, fmt.Errorf("invalid range %q", s)
+		}
+		s = s[:i]
+	}
+	if start, err = strconv.Atoi(s); err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+	return start, lines, nil
+}
+
+// changed reports whether any line from start to end is changed.
+func (c changes) changed(start, end int, filename string) bool {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return false
+	}
+	file, ok := c[abs]
+	if !ok {
+		return false
+	}
+	if file.all {
+		return true
+	}
+	if end < start {
+		end = start
+	}
+	for line := start; line <= end; line++ {
+		if file.lines[line] {
+			return true
+		}
+	}
+	return false
+}
+
+// filter removes problems not on changed lines.
+// A problem is new if its lines or a related location like the header of the loop declaring its variable is changed.
+func (c changes) filter(problems []scopelint.Problem) []scopelint.Problem {
+	var filtered []scopelint.Problem
+	for _, p := range problems {
+		end := p.End.Line
+		if p.End.Filename != p.Position.Filename {
+			end = p.Position.Line
+		}
+		isNew := c.changed(p.Position.Line, end, p.Position.Filename)
+		for _, related := range p.Related {
+			isNew = isNew || c.changed(related.Position.Line, related.Position.Line, related.Position.Filename)
+		}
+		if isNew {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	gotoken "go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kyoh86/scopelint/scopelint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDiff(t *testing.T) {
+	c, err := parseDiff(strings.NewReader(`diff --git a/a.go b/a.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/a.go
+@@ -2,3 +2,4 @@ package p
+ context
+-removed
++added
++++ added like a header
+ context
+@@ -10 +11 @@
+-old
++new
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+--- a/gone.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package p
+-+++ b/fake.go
+--- "a/sp ace.go"	2019-01-01 00:00:00
++++ "b/sp ace.go"	2019-01-01 00:00:00
+@@ -0,0 +1 @@
++package p
+\ No newline at end of file
+`), func(name string) string { return name })
+	require.NoError(t, err)
+	require.Len(t, c, 2)
+	assert.Equal(t, map[int]bool{3: true, 4: true, 11: true}, c["b/a.go"].lines)
+	assert.Equal(t, map[int]bool{1: true}, c["b/sp ace.go"].lines)
+
+	_, err = parseDiff(strings.NewReader("+++ b/a.go\n@@ -1 +1 @@\nbroken\n"), func(name string) string { return name })
+	assert.Error(t, err)
+}
+
+func TestChangesFilter(t *testing.T) {
+	abs, err := filepath.Abs("p.go")
+	require.NoError(t, err)
+	c := changes{abs: &fileChanges{lines: map[int]bool{3: true, 10: true}}}
+
+	problem := func(line, loop int) scopelint.Problem {
+		return scopelint.Problem{
+			Text:     "p",
+			Position: gotoken.Position{Filename: "p.go", Line: line},
+			End:      gotoken.Position{Filename: "p.go", Line: line},
+			Related:  []scopelint.RelatedLocation{{Position: gotoken.Position{Filename: "p.go", Line: loop}}},
+		}
+	}
+	filtered := c.filter([]scopelint.Problem{
+		problem(3, 1),  // on a changed line
+		problem(5, 3),  // the loop header is changed
+		problem(20, 4), // not changed
+	})
+	require.Len(t, filtered, 2)
+	assert.Equal(t, 3, filtered[0].Position.Line)
+	assert.Equal(t, 5, filtered[1].Position.Line)
+}
+
+func TestRevisionChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git command is not found")
+	}
+	dir, err := ioutil.TempDir("", "scopelint-rev")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	run := func(args ...string) {
+		_, err := git(dir, append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		require.NoError(t, err)
+	}
+	write := func(name, content string) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	run("init", "-q")
+	write("a.go", "package p\n\nvar a = 1\n")
+	run("add", "a.go")
+	run("commit", "-q", "-m", "init")
+	write("a.go", "package p\n\nvar a = 2\n")
+	write("new.go", "package p\n")
+
+	c, err := revisionChanges(dir, "HEAD")
+	require.NoError(t, err)
+	assert.True(t, c.changed(3, 3, filepath.Join(dir, "a.go")))
+	assert.False(t, c.changed(1, 2, filepath.Join(dir, "a.go")))
+	assert.True(t, c.changed(1, 1, filepath.Join(dir, "new.go")), "untracked files are new")
+
+	_, err = revisionChanges(dir, "no-such-rev")
+	assert.Error(t, err)
+}
+
+func TestPatchFilename(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git command is not found")
+	}
+	dir, err := ioutil.TempDir("", "scopelint-patch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dir, err = filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	sub := filepath.Join(dir, "sub")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sub, "x.go"), []byte("package sub\n"), 0644))
+
+	filename := patchFilename(sub)
+	assert.Equal(t, filepath.Join(sub, "x.go"), filename("b/x.go"))
+	assert.Equal(t, filepath.Join(sub, "y.go"), filename("b/y.go"), "files out of repositories are in the directory")
+
+	_, err = git(dir, "init", "-q")
+	require.NoError(t, err)
+	filename = patchFilename(sub)
+	assert.Equal(t, filepath.Join(sub, "x.go"), filename("b/sub/x.go"), "paths of git diff are relative to the top level")
+	assert.Equal(t, filepath.Join(sub, "x.go"), filename("sub/x.go"))
+	assert.Equal(t, filepath.Join(sub, "x.go"), filename("x.go"))
+}
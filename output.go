@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// outputSpec is an output of reports given by the --output flag like `sarif=report.sarif,min-confidence=0.8`.
+//
+// Without a format (`report.txt`), the main report in the --format is written to the path instead of stdout.
+// The path "-" means stdout.
+type outputSpec struct {
+	format         string // empty for the main report
+	path           string
+	showIgnored    bool
+	showIgnoredSet bool // whether show-ignored is given in the value
+	minConfidence  float64
+}
+
+// activeOnlyFormats are the formats which never report ignored problems.
+var activeOnlyFormats = map[string]bool{"junit": true, "codeclimate": true}
+
+// parseOutputSpec parses a value of the --output flag.
+// Filters not in the value default to the ones given by the other flags.
+func parseOutputSpec(value string) (outputSpec, error) {
+	spec := outputSpec{showIgnored: params.showIgnored}
+	fields := strings.Split(value, ",")
+	spec.path = fields[0]
+	if i := strings.Index(spec.path, "="); i >= 0 && isFormat(spec.path[:i]) {
+		spec.format, spec.path = spec.path[:i], spec.path[i+1:]
+	}
+	if spec.path == "" {
+		return spec, fmt.Errorf("invalid output %q: no path", value)
+	}
+	for _, option := range fields[1:] {
+		name, arg := option, ""
+		if i := strings.Index(option, "="); i >= 0 {
+			name, arg = option[:i], option[i+1:]
+		}
+		switch name {
+		case "show-ignored":
+			spec.showIgnored, spec.showIgnoredSet = true, true
+			if arg != "" {
+				b, err := strconv.ParseBool(arg)
+				if err != nil {
+					return spec, fmt.Errorf("invalid output %q: %v", value, err)
+				}
+				spec.showIgnored = b
+			}
+		case "min-confidence":
+			f, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return spec, fmt.Errorf("invalid output %q: %v", value, err)
+			}
+			spec.minConfidence = f
+		default:
+			return spec, fmt.Errorf("invalid output %q: unknown option %q", value, name)
+		}
+	}
+	return spec, nil
+}
+
+// openOutputs creates a reporter which fans problems out to the reporters of the --output flags.
+// The main report in the format is written to stdout unless an output without a format is given.
+func openOutputs(format string, values []string) (reporter, error) {
+	main := outputSpec{format: format, path: "-", showIgnored: params.showIgnored}
+	var specs []outputSpec
+	for _, value := range values {
+		spec, err := parseOutputSpec(value)
+		if err != nil {
+			return nil, &configError{err}
+		}
+		if spec.format == "" {
+			spec.format = format
+			main.path = ""
+		}
+		specs = append(specs, spec)
+	}
+	if main.path != "" {
+		specs = append([]outputSpec{main}, specs...)
+	}
+
+	for _, spec := range specs {
+		if spec.showIgnoredSet && spec.showIgnored && activeOnlyFormats[spec.format] {
+			return nil, &configError{fmt.Errorf("invalid output %s: the %s format does not report ignored problems", spec.path, spec.format)}
+		}
+	}
+
+	var outputs multiReporter
+	for _, spec := range specs {
+		// Stdout is passed as is, so that reporters can detect terminals.
+		var w io.Writer = os.Stdout
+		var closer io.Closer = nopCloser{}
+		if spec.path != "-" {
+			f, err := os.Create(spec.path)
+			if err != nil {
+				outputs.Close()
+				return nil, err
+			}
+			w, closer = f, f
+		}
+		r, err := newReporter(spec.format, w, spec.showIgnored)
+		if err != nil {
+			closer.Close()
+			outputs.Close()
+			return nil, &configError{err}
+		}
+		if spec.showIgnoredSet && !spec.showIgnored {
+			// Some formats like json and sarif report ignored problems regardless of showIgnored.
+			r = &ignoredFilter{reporter: r}
+		}
+		outputs = append(outputs, &fileReporter{
+			reporter: &confidenceFilter{reporter: r, min: spec.minConfidence},
+			w:        closer,
+		})
+	}
+	return outputs, nil
+}
+
+// multiReporter reports problems to all of the reporters.
+type multiReporter []reporter
+
+func (m multiReporter) Report(pkg string, problems []scopelint.Problem) error {
+	var first error
+	for _, r := range m {
+		if err := r.Report(pkg, problems); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (m multiReporter) ReportError(e lintError) error {
+	var first error
+	for _, r := range m {
+		if err := reportError(r, e); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (m multiReporter) Close() error {
+	var first error
+	for _, r := range m {
+		if err := r.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// confidenceFilter drops problems whose confidence is less than min.
+type confidenceFilter struct {
+	reporter
+	min float64
+}
+
+func (f *confidenceFilter) Report(pkg string, problems []scopelint.Problem) error {
+	if f.min <= 0 {
+		return f.reporter.Report(pkg, problems)
+	}
+	filtered := make([]scopelint.Problem, 0, len(problems))
+	for _, p := range problems {
+		if p.Confidence >= f.min {
+			filtered = append(filtered, p)
+		}
+	}
+	return f.reporter.Report(pkg, filtered)
+}
+
+func (f *confidenceFilter) ReportError(e lintError) error {
+	return reportError(f.reporter, e)
+}
+
+// ignoredFilter drops problems ignored by directives.
+type ignoredFilter struct {
+	reporter
+}
+
+func (f *ignoredFilter) Report(pkg string, problems []scopelint.Problem) error {
+	active := make([]scopelint.Problem, 0, len(problems))
+	for _, p := range problems {
+		if !p.Ignored {
+			active = append(active, p)
+		}
+	}
+	return f.reporter.Report(pkg, active)
+}
+
+func (f *ignoredFilter) ReportError(e lintError) error {
+	return reportError(f.reporter, e)
+}
+
+// fileReporter closes the writer after the reporter is closed.
+type fileReporter struct {
+	reporter
+	w io.Closer
+}
+
+func (f *fileReporter) ReportError(e lintError) error {
+	return reportError(f.reporter, e)
+}
+
+func (f *fileReporter) Close() error {
+	err := f.reporter.Close()
+	if cerr := f.w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// nopCloser is the closer of stdout, which is never closed.
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
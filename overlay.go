@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// stdinArgument is the argument to read the source of the file given by --stdin-filename from stdin.
+const stdinArgument = "-"
+
+// overlay replaces the contents of files with others, like the -overlay flag of go build.
+// Files in the overlay are read instead of the ones on disk, even if they do not exist on disk.
+type overlay struct {
+	replace  map[string]string // absolute path => path of the replacement file, or empty for a deleted file
+	contents map[string][]byte // absolute path => content read from stdin
+}
+
+// overlayFile is the JSON file given by --overlay, in the same shape as the one of go build.
+type overlayFile struct {
+	Replace map[string]string
+}
+
+var sourceOverlay = &overlay{replace: map[string]string{}, contents: map[string][]byte{}}
+
+// setupOverlay reads the file given by --overlay and the source given from stdin,
+// and lets the build context read files through the overlay.
+func setupOverlay(stdin io.Reader) error {
+	if params.overlay != "" {
+		raw, err := ioutil.ReadFile(params.overlay)
+		if err != nil {
+			return err
+		}
+		var file overlayFile
+		if err := json.Unmarshal(raw, &file); err != nil {
+			return fmt.Errorf("invalid overlay %s: %v", params.overlay, err)
+		}
+		for from, to := range file.Replace {
+			abs, err := filepath.Abs(from)
+			if err != nil {
+				return err
+			}
+			sourceOverlay.replace[abs] = to
+		}
+	}
+	if params.stdinFilename != "" && !params.arguments.stdin {
+		return &configError{fmt.Errorf("--stdin-filename needs the argument %s to read the source from stdin", stdinArgument)}
+	}
+	if params.arguments.stdin {
+		if params.stdinFilename == "" {
+			return &configError{fmt.Errorf("reading from stdin needs --stdin-filename")}
+		}
+		src, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		abs, err := filepath.Abs(params.stdinFilename)
+		if err != nil {
+			return err
+		}
+		sourceOverlay.contents[abs] = src
+	}
+	if len(sourceOverlay.replace) > 0 || len(sourceOverlay.contents) > 0 {
+		buildContext.ReadDir = sourceOverlay.readDir
+		buildContext.OpenFile = sourceOverlay.openFile
+		buildContext.IsDir = sourceOverlay.isDir
+	}
+	return nil
+}
+
+// lookup returns the content or the replacement of the file in the overlay.
+func (o *overlay) lookup(filename string) (content []byte, replacement string, ok bool) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, "", false
+	}
+	if content, ok := o.contents[abs]; ok {
+		return content, "", true
+	}
+	replacement, ok = o.replace[abs]
+	return nil, replacement, ok
+}
+
+// readFile reads the file through the overlay.
+func (o *overlay) readFile(filename string) ([]byte, error) {
+	content, replacement, ok := o.lookup(filename)
+	switch {
+	case !ok:
+		return ioutil.ReadFile(filename)
+	case content != nil:
+		return content, nil
+	case replacement == "":
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+	}
+	return ioutil.ReadFile(replacement)
+}
+
+func (o *overlay) openFile(filename string) (io.ReadCloser, error) {
+	src, err := o.readFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(src)), nil
+}
+
+// readDir lists the files in the directory through the overlay.
+func (o *overlay) readDir(dir string) ([]os.FileInfo, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := map[string]os.FileInfo{}
+	list, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, fi := range list {
+		entries[fi.Name()] = fi
+	}
+	for _, name := range o.names(abs) {
+		src, err := o.readFile(filepath.Join(abs, name))
+		if err != nil {
+			delete(entries, name)
+			continue
+		}
+		entries[name] = overlayFileInfo{name: name, size: int64(len(src))}
+	}
+	if len(entries) == 0 && os.IsNotExist(err) {
+		return nil, err
+	}
+
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, fi := range entries {
+		infos = append(infos, fi)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+func (o *overlay) isDir(dir string) bool {
+	if isDirExists(dir) {
+		return true
+	}
+	abs, err := filepath.Abs(dir)
+	return err == nil && len(o.names(abs)) > 0
+}
+
+// names returns the names of the files in the overlay in the directory.
+func (o *overlay) names(dir string) []string {
+	var names []string
+	for abs := range o.replace {
+		if filepath.Dir(abs) == dir {
+			names = append(names, filepath.Base(abs))
+		}
+	}
+	for abs := range o.contents {
+		if filepath.Dir(abs) == dir {
+			names = append(names, filepath.Base(abs))
+		}
+	}
+	return names
+}
+
+// overlayFileInfo is the os.FileInfo of a file in the overlay.
+type overlayFileInfo struct {
+	name string
+	size int64
+}
+
+func (fi overlayFileInfo) Name() string       { return fi.name }
+func (fi overlayFileInfo) Size() int64        { return fi.size }
+func (fi overlayFileInfo) Mode() os.FileMode  { return 0644 }
+func (fi overlayFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi overlayFileInfo) IsDir() bool        { return false }
+func (fi overlayFileInfo) Sys() interface{}   { return nil }
+
+// stdinProblems returns the problems in the file given by --stdin-filename.
+// The other files of its package are linted as the context, but their problems are not reported,
+// so that editors can tell whether the buffer is clean.
+func stdinProblems(problems []scopelint.Problem) []scopelint.Problem {
+	abs, err := filepath.Abs(params.stdinFilename)
+	if err != nil {
+		return problems
+	}
+	var filtered []scopelint.Problem
+	for _, p := range problems {
+		if filename, err := filepath.Abs(p.Position.Filename); err == nil && filename == abs {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
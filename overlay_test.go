@@ -0,0 +1,108 @@
+package main
+
+import (
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kyoh86/scopelint/scopelint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+func TestOverlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scopelint-overlay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		name = filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(name, []byte(content), 0644))
+		return name
+	}
+	kept := write("kept.go", "package p // kept\n")
+	replaced := write("replaced.go", "package p // on disk\n")
+	deleted := write("deleted.go", "package p // deleted\n")
+	replacement := write("replacement.txt", "package p // replacement\n")
+
+	o := &overlay{
+		replace: map[string]string{
+			replaced: replacement,
+			deleted:  "",
+		},
+		contents: map[string][]byte{
+			filepath.Join(dir, "stdin.go"): []byte("package p // stdin\n"),
+		},
+	}
+
+	infos, err := o.readDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+	}
+	assert.Equal(t, []string{"kept.go", "replaced.go", "replacement.txt", "stdin.go"}, names)
+
+	for filename, want := range map[string]string{
+		kept:                           "package p // kept\n",
+		replaced:                       "package p // replacement\n",
+		filepath.Join(dir, "stdin.go"): "package p // stdin\n",
+	} {
+		src, err := o.readFile(filename)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(src))
+	}
+	_, err = o.readFile(deleted)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestStdinArguments(t *testing.T) {
+	app := kingpin.New("test", "")
+	app.Flag("output", "").Short('o').String()
+	app.Flag("verbose", "").Bool()
+	model := app.Model()
+
+	assert.Equal(t, []string{"--stdin-filename", "a.go", "--", "-"}, stdinArguments(model, []string{"--stdin-filename", "a.go", "-"}))
+	assert.Equal(t, []string{"--verbose", "--", "-"}, stdinArguments(model, []string{"--verbose", "-"}))
+	assert.Equal(t, []string{"-o", "-", "./..."}, stdinArguments(model, []string{"-o", "-", "./..."}))
+	assert.Equal(t, []string{"--", "-", "x"}, stdinArguments(model, []string{"-", "--", "x"}))
+}
+
+func TestStdinProblems(t *testing.T) {
+	saved := params.stdinFilename
+	defer func() { params.stdinFilename = saved }()
+	params.stdinFilename = filepath.Join("p", "z.go")
+
+	problems := []scopelint.Problem{
+		{Position: token.Position{Filename: filepath.Join("p", "a.go"), Line: 3}},
+		{Position: token.Position{Filename: filepath.Join("p", "z.go"), Line: 5}},
+	}
+	abs, err := filepath.Abs(filepath.Join("p", "z.go"))
+	require.NoError(t, err)
+	problems = append(problems, scopelint.Problem{Position: token.Position{Filename: abs, Line: 7}})
+
+	filtered := stdinProblems(problems)
+	require.Len(t, filtered, 2, "problems in the other files of the package are not reported")
+	assert.Equal(t, 5, filtered[0].Position.Line)
+	assert.Equal(t, 7, filtered[1].Position.Line)
+	assert.Empty(t, stdinProblems(problems[:1]))
+}
+
+func TestSetupOverlayStdin(t *testing.T) {
+	saved, savedArgs := params.stdinFilename, params.arguments
+	defer func() { params.stdinFilename, params.arguments = saved, savedArgs }()
+
+	params.stdinFilename, params.arguments = "z.go", arguments{}
+	err := setupOverlay(strings.NewReader("package p\n"))
+	require.Error(t, err, "--stdin-filename is not ignored without -")
+	assert.IsType(t, &configError{}, err)
+
+	params.stdinFilename, params.arguments = "", arguments{stdin: true}
+	err = setupOverlay(strings.NewReader("package p\n"))
+	require.Error(t, err)
+	assert.IsType(t, &configError{}, err)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// formats are the names of output formats.
+var formats = []string{defaultFormat, "json", "jsonl", "sarif", "checkstyle", "junit", "github", "rdjsonl", "codeclimate", "markdown", "html", templateFormat}
+
+const defaultFormat = "text"
+
+func isFormat(name string) bool {
+	if strings.HasPrefix(name, templatePrefix) {
+		return true
+	}
+	for _, f := range formats {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// newReporter creates a reporter for the output format.
+func newReporter(format string, w io.Writer, showIgnored bool) (reporter, error) {
+	if !isFormat(format) {
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if format == templateFormat || strings.HasPrefix(format, templatePrefix) {
+		return newTemplateReporter(w, format, params.templateFile, showIgnored)
+	}
+	switch format {
+	case "json":
+		return &jsonReporter{w: w}, nil
+	case "jsonl":
+		return newJSONLinesReporter(w), nil
+	case "sarif":
+		return &sarifReporter{w: w}, nil
+	case "github":
+		return &githubReporter{w: w, showIgnored: showIgnored}, nil
+	case "checkstyle":
+		return newCheckstyleReporter(w, showIgnored), nil
+	case "junit":
+		return newJUnitReporter(w), nil
+	case "rdjsonl":
+		return newRDJSONLReporter(w, showIgnored), nil
+	case "codeclimate":
+		return newCodeClimateReporter(w), nil
+	case "markdown":
+		return newMarkdownReporter(w, showIgnored), nil
+	case "html":
+		return newHTMLReporter(w), nil
+	default:
+		return newTextReporter(w, showIgnored), nil
+	}
+}
+
+// problemSeverity returns the severity of the problem configured for the directory of the file.
+func problemSeverity(p scopelint.Problem) string {
+	cfg, err := loadConfig(filepath.Dir(p.Position.Filename))
+	if err != nil {
+		return severityError
+	}
+	return cfg.severity(p.Category)
+}
+
+// relativePath returns a slash-separated path of the file relative to the working directory.
+// If the file is out of the working directory, it returns an absolute path and false.
+func relativePath(filename string) (string, bool) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return filepath.ToSlash(filename), !filepath.IsAbs(filename)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel), true
+		}
+	}
+	return filepath.ToSlash(abs), false
+}
+
+// sourceCache caches the lines of source files read by reporters.
+type sourceCache map[string][]string
+
+// lines returns the lines of the file, or nil if it cannot be read.
+func (c sourceCache) lines(filename string) []string {
+	lines, ok := c[filename]
+	if !ok {
+		if src, err := sourceOverlay.readFile(filename); err == nil {
+			lines = strings.Split(strings.TrimSuffix(string(src), "\n"), "\n")
+		}
+		c[filename] = lines
+	}
+	return lines
+}
+
+// reporter reports problems found in linted packages.
+type reporter interface {
+	// Report reports problems found in a package.
+	// Problems ignored by directives are included and marked as Ignored.
+	Report(pkg string, problems []scopelint.Problem) error
+	// Close finishes the report after all packages are linted.
+	Close() error
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kyoh86/scopelint/scopelint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := &githubReporter{w: &buf}
+	require.NoError(t, r.Report("pkg", []scopelint.Problem{{
+		Position:   token.Position{Filename: "a,b.go", Line: 7, Column: 20},
+		Text:       "100% wrong\nreally",
+		Category:   "range-scope",
+		Confidence: 1,
+	}, {
+		Position: token.Position{Filename: "a.go", Line: 9, Column: 1},
+		Text:     "ignored",
+		Category: "range-scope",
+		Ignored:  true,
+	}}))
+	require.NoError(t, r.Close())
+	assert.Equal(t, "::error file=a%2Cb.go,line=7,col=20,title=scopelint(range-scope)::100%25 wrong%0Areally\n", buf.String())
+}
+
+func TestCodeClimateReporter(t *testing.T) {
+	problem := scopelint.Problem{
+		Position:   token.Position{Filename: "a.go", Line: 7, Column: 20},
+		Text:       "Using a reference for the variable on range scope \"i\"",
+		Category:   "range-scope",
+		Confidence: 1,
+		LineText:   "\tf(&i, &i)",
+		Variable:   "i",
+	}
+	report := func() []codeClimateIssue {
+		var buf bytes.Buffer
+		r := newCodeClimateReporter(&buf)
+		require.NoError(t, r.Report("pkg", []scopelint.Problem{problem, problem}))
+		require.NoError(t, r.Close())
+		var issues []codeClimateIssue
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &issues))
+		return issues
+	}
+
+	issues := report()
+	require.Len(t, issues, 2)
+	assert.NotEqual(t, issues[0].Fingerprint, issues[1].Fingerprint)
+	assert.Equal(t, issues, report(), "fingerprints should be stable across runs")
+
+	problem.Position.Line = 8
+	moved := report()
+	assert.Equal(t, issues[0].Fingerprint, moved[0].Fingerprint, "fingerprints should be stable against line shifts")
+}
+
+// xmlPackages are problems in packages to test XML reporters.
+var xmlPackages = []struct {
+	name     string
+	problems []scopelint.Problem
+}{
+	{"example.com/a", []scopelint.Problem{
+		{Position: token.Position{Filename: "a/a.go", Line: 3, Column: 10}, Text: `Using "v" & "w"`, Category: "range-scope", Confidence: 1},
+		{Position: token.Position{Filename: "a/a.go", Line: 5, Column: 2}, Text: "second", Category: "range-scope", Confidence: 1},
+		{Position: token.Position{Filename: "a/b.go", Line: 4, Column: 8}, Text: "ignored", Category: "range-scope", Confidence: 1, Ignored: true},
+		{Position: token.Position{Filename: "a/b.go", Line: 9, Column: 8}, Text: "in b", Category: "range-scope", Confidence: 1},
+	}},
+	{"example.com/b", nil},
+	{"example.com/c", []scopelint.Problem{
+		{Position: token.Position{Filename: "c/c.go", Line: 1, Column: 1}, Text: "in c", Category: "range-scope", Confidence: 1},
+	}},
+}
+
+func TestCheckstyleReporter(t *testing.T) {
+	report := func(showIgnored bool) []checkstyleFile {
+		var buf bytes.Buffer
+		r := newCheckstyleReporter(&buf, showIgnored)
+		for i, pkg := range xmlPackages {
+			require.NoError(t, r.Report(pkg.name, pkg.problems))
+			if i == 0 {
+				assert.Contains(t, buf.String(), `<file name="a/b.go">`, "files are written as packages are reported")
+			}
+		}
+		require.NoError(t, r.Close())
+		var checkstyle struct {
+			Version string           `xml:"version,attr"`
+			Files   []checkstyleFile `xml:"file"`
+		}
+		require.NoError(t, xml.Unmarshal(buf.Bytes(), &checkstyle), buf.String())
+		assert.Equal(t, "5.0", checkstyle.Version)
+		return checkstyle.Files
+	}
+
+	files := report(false)
+	require.Len(t, files, 3, "a file element for each file")
+	assert.Equal(t, "a/a.go", files[0].Name)
+	assert.Equal(t, []checkstyleError{
+		{Line: 3, Column: 10, Severity: severityError, Message: `Using "v" & "w"`, Source: "scopelint.range-scope"},
+		{Line: 5, Column: 2, Severity: severityError, Message: "second", Source: "scopelint.range-scope"},
+	}, files[0].Errors)
+	assert.Equal(t, "a/b.go", files[1].Name)
+	assert.Len(t, files[1].Errors, 1, "ignored problems are left out")
+	assert.Equal(t, "c/c.go", files[2].Name)
+
+	files = report(true)
+	require.Len(t, files, 3)
+	require.Len(t, files[1].Errors, 2)
+	assert.Equal(t, severityInfo, files[1].Errors[0].Severity)
+	assert.Equal(t, "ignored (ignored)", files[1].Errors[0].Message)
+}
+
+func TestJUnitReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := newJUnitReporter(&buf)
+	for i, pkg := range xmlPackages {
+		require.NoError(t, r.Report(pkg.name, pkg.problems))
+		if i == 0 {
+			assert.Contains(t, buf.String(), `<testsuite name="example.com/a"`, "test suites are written as packages are reported")
+		}
+	}
+	require.NoError(t, r.Close())
+	var junit struct {
+		Suites []junitTestSuite `xml:"testsuite"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &junit), buf.String())
+	require.Len(t, junit.Suites, 3, "a test suite for each package")
+	for i, want := range []struct {
+		name     string
+		failures int
+	}{{"example.com/a", 3}, {"example.com/b", 0}, {"example.com/c", 1}} {
+		suite := junit.Suites[i]
+		assert.Equal(t, want.name, suite.Name)
+		assert.Equal(t, 1, suite.Tests)
+		assert.Equal(t, want.failures, suite.Failures, "ignored problems are not failures")
+		require.Len(t, suite.TestCases, 1)
+		assert.Len(t, suite.TestCases[0].Failures, want.failures)
+	}
+	assert.Equal(t, "a/a.go:3:10: Using \"v\" & \"w\" (error)", junit.Suites[0].TestCases[0].Failures[0].Text)
+}
+
+func TestTemplateReporter(t *testing.T) {
+	problems := []scopelint.Problem{{
+		Position: token.Position{Filename: "a.go", Line: 7, Column: 20},
+		Text:     `range scope "i"`,
+		Category: "range-scope",
+	}, {
+		Position: token.Position{Filename: "a.go", Line: 9, Column: 1},
+		Text:     "ignored",
+		Category: "range-scope",
+		Ignored:  true,
+	}}
+
+	t.Run("inline", func(t *testing.T) {
+		var buf bytes.Buffer
+		r, err := newTemplateReporter(&buf, "template={{.Position.Filename}}|{{.Position.Line}}|{{json .Text}}", "", false)
+		require.NoError(t, err)
+		require.NoError(t, r.Report("pkg", problems))
+		require.NoError(t, r.Close())
+		assert.Equal(t, "a.go|7|\"range scope \\\"i\\\"\"\n", buf.String())
+	})
+
+	t.Run("file", func(t *testing.T) {
+		file, err := ioutil.TempFile("", "scopelint-template")
+		require.NoError(t, err)
+		defer os.Remove(file.Name())
+		_, err = file.WriteString(`{{define "header"}}{{.Tool.Name}}
+{{end}}
+{{- define "problem"}}{{.Position.Line}}
+{{end}}
+{{- define "footer"}}{{.Totals.Active}}/{{.Totals.Ignored}} in {{join .Packages ","}}
+{{end}}`)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		var buf bytes.Buffer
+		r, err := newTemplateReporter(&buf, templateFormat, file.Name(), false)
+		require.NoError(t, err)
+		require.NoError(t, r.Report("pkg", problems))
+		require.NoError(t, r.Close())
+		assert.Equal(t, "scopelint\n7\n1/1 in pkg\n", buf.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := newTemplateReporter(ioutil.Discard, "template={{", "", false)
+		assert.Error(t, err)
+		_, err = newTemplateReporter(ioutil.Discard, templateFormat, "", false)
+		assert.Error(t, err)
+	})
+}
+
+func TestTextReporterFrames(t *testing.T) {
+	file, err := ioutil.TempFile("", "scopelint-frame")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	src := []byte("package a\n\nfunc f() {\n\tfor _, i := range []int{1} {\n\t\t_ = &i\n\t}\n}\n")
+	_, err = file.Write(src)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	problems, err := new(scopelint.Linter).Lint(file.Name(), src)
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+
+	var buf bytes.Buffer
+	r := &textReporter{w: &buf, frames: true, sources: sourceCache{}}
+	require.NoError(t, r.Report("pkg", problems))
+	assert.Equal(t, file.Name()+`:5:8: range-scope: Using a reference for the variable on range scope "i"
+4 | 	for _, i := range []int{1} {
+  | 	 - The variable "i" is declared in the loop here
+5 | 		_ = &i
+  | 		     ^
+  | help: Pin the variable "i" in the loop body: i := i // pin
+`, buf.String())
+}
+
+func TestSarifColumns(t *testing.T) {
+	file, err := ioutil.TempFile("", "scopelint-sarif")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	src := []byte("package a\n\nfunc f() (ps []*int) {\n\tfor _, i := range []int{1} { _ = \"日本😀\"; ps = append(ps, &i) }\n\treturn\n}\n")
+	_, err = file.Write(src)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	problems, err := new(scopelint.Linter).Lint(file.Name(), src)
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	require.Equal(t, 66, problems[0].Position.Column, "the column in bytes")
+
+	var buf bytes.Buffer
+	r := &sarifReporter{w: &buf}
+	require.NoError(t, r.Report("pkg", problems))
+	require.NoError(t, r.Close())
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	run := log.Runs[0]
+	assert.Equal(t, "utf16CodeUnits", run.ColumnKind)
+	// "日本" is 6 bytes in 2 units, and "😀" is 4 bytes in 2 units.
+	assert.Equal(t, 60, run.Results[0].Locations[0].PhysicalLocation.Region.StartColumn)
+	assert.Equal(t, 2, run.Results[0].RelatedLocations[0].PhysicalLocation.Region.StartColumn)
+	assert.Equal(t, 30, run.Results[0].Fixes[0].ArtifactChanges[0].Replacements[0].DeletedRegion.StartColumn)
+}
+
+func TestEditDiff(t *testing.T) {
+	lines := []string{"func f() {", "\tfor _, i := range xs {", "\t\t_ = &i", "\t}", "}"}
+	assert.Equal(t, []string{" \tfor _, i := range xs {", "+\t\ti := i // pin"}, editDiff(lines, scopelint.Edit{
+		Position: token.Position{Line: 2, Column: 24},
+		End:      token.Position{Line: 2, Column: 24},
+		NewText:  "\n\t\ti := i // pin",
+	}))
+	assert.Equal(t, []string{"-\t\t_ = &i", "+\t\t_ = i"}, editDiff(lines, scopelint.Edit{
+		Position: token.Position{Line: 3, Column: 7},
+		End:      token.Position{Line: 3, Column: 9},
+		NewText:  "i",
+	}))
+	assert.Equal(t, []string{"+i := i"}, editDiff(nil, scopelint.Edit{
+		Position: token.Position{Line: 2, Column: 24},
+		End:      token.Position{Line: 2, Column: 24},
+		NewText:  "\ni := i",
+	}))
+}
+
+func TestMarkdownReporterTruncation(t *testing.T) {
+	var problems []scopelint.Problem
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		problems = append(problems, scopelint.Problem{
+			Position: token.Position{Filename: name, Line: 1, Column: 1},
+			Text:     "problem",
+			Category: "range-scope",
+			LineText: "_ = &i",
+		})
+	}
+
+	var buf bytes.Buffer
+	r := newMarkdownReporter(&buf, false)
+	r.limit = 400
+	require.NoError(t, r.Report("pkg", problems))
+	require.NoError(t, r.Close())
+	assert.Contains(t, buf.String(), "| `pkg` | range-scope | 3 | 0 |")
+	assert.Contains(t, buf.String(), "<code>a.go</code>")
+	assert.NotContains(t, buf.String(), "<code>c.go</code>")
+	assert.Contains(t, buf.String(), "in 2 files are omitted.")
+}
+
+func TestMarkdownReporterTableTruncation(t *testing.T) {
+	var buf bytes.Buffer
+	r := newMarkdownReporter(&buf, false)
+	r.limit = 400
+	for i := 0; i < 20; i++ {
+		pkg := fmt.Sprintf("example.com/m/p%02d", i)
+		require.NoError(t, r.Report(pkg, []scopelint.Problem{
+			{Position: token.Position{Filename: pkg + "/a.go", Line: 1, Column: 1}, Text: "problem", Category: "range-scope"},
+			{Position: token.Position{Filename: pkg + "/a.go", Line: 2, Column: 1}, Text: "problem", Category: "unpinned"},
+		}))
+	}
+	require.NoError(t, r.Close())
+	assert.True(t, buf.Len() <= 400, "the report is in the limit: %d", buf.Len())
+	assert.Contains(t, buf.String(), "Found 40 problems")
+	assert.Contains(t, buf.String(), "| `example.com/m/p00` | range-scope | 1 | 0 |")
+	assert.NotContains(t, buf.String(), "p19")
+	assert.Regexp(t, `\| … and \d+ more packages \| \| \| \|`, buf.String())
+}
+
+func TestHighlightGo(t *testing.T) {
+	lines := highlightGo([]byte("for i := 0; i < 1; i++ { // <loop>\n\ts := \"a\\nb\"\n}\n"))
+	require.Len(t, lines, 3)
+	assert.Equal(t, `<span class="k">for</span> i := <span class="n">0</span>; i &lt; <span class="n">1</span>; i++ { <span class="c">// &lt;loop&gt;</span>`, string(lines[0]))
+	assert.Equal(t, "\ts := <span class=\"s\">&#34;a\\nb&#34;</span>", string(lines[1]))
+	assert.Equal(t, "}", string(lines[2]))
+
+	lines = highlightGo([]byte("s := \"日本語\" // ü\n"))
+	require.Len(t, lines, 1)
+	assert.Equal(t, `s := <span class="s">&#34;日本語&#34;</span> <span class="c">// ü</span>`, string(lines[0]), "non-ASCII characters are kept")
+}
+
+func TestParseOutputSpec(t *testing.T) {
+	for _, c := range []struct {
+		value string
+		want  outputSpec
+	}{
+		{"report.txt", outputSpec{path: "report.txt"}},
+		{"sarif=report.sarif", outputSpec{format: "sarif", path: "report.sarif"}},
+		{"sarif=out/report.sarif,show-ignored,min-confidence=0.8", outputSpec{format: "sarif", path: "out/report.sarif", showIgnored: true, showIgnoredSet: true, minConfidence: 0.8}},
+		{"text=-,show-ignored=false", outputSpec{format: "text", path: "-", showIgnoredSet: true}},
+		{"a=b.txt", outputSpec{path: "a=b.txt"}},
+	} {
+		spec, err := parseOutputSpec(c.value)
+		require.NoError(t, err, c.value)
+		assert.Equal(t, c.want, spec, c.value)
+	}
+	for _, value := range []string{"sarif=", "report.txt,unknown", "report.txt,min-confidence=high"} {
+		_, err := parseOutputSpec(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestOutputShowIgnored(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scopelint-outputs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	problems := []scopelint.Problem{
+		{Position: token.Position{Filename: "a.go", Line: 1, Column: 1}, Text: "active"},
+		{Position: token.Position{Filename: "a.go", Line: 2, Column: 1}, Text: "ignored", Ignored: true},
+	}
+
+	shown, hidden := filepath.Join(dir, "shown.json"), filepath.Join(dir, "hidden.json")
+	outputs, err := openOutputs("json", []string{shown, "json=" + hidden + ",show-ignored=false"})
+	require.NoError(t, err)
+	require.NoError(t, outputs.Report("pkg", problems))
+	require.NoError(t, outputs.Close())
+	for filename, want := range map[string]int{shown: 2, hidden: 1} {
+		raw, err := ioutil.ReadFile(filename)
+		require.NoError(t, err)
+		var report jsonReport
+		require.NoError(t, json.Unmarshal(raw, &report))
+		assert.Len(t, report.Packages[0].Problems, want, filename)
+	}
+
+	_, err = openOutputs("junit", []string{filepath.Join(dir, "junit.xml") + ",show-ignored"})
+	require.Error(t, err, "junit never reports ignored problems")
+	assert.IsType(t, &configError{}, err)
+	_, err = os.Stat(filepath.Join(dir, "junit.xml"))
+	assert.True(t, os.IsNotExist(err), "no files are created for invalid outputs")
+}
+
+func TestOutputStdout(t *testing.T) {
+	outputs, err := openOutputs("text", nil)
+	require.NoError(t, err)
+	main := outputs.(multiReporter)[0].(*fileReporter).reporter.(*confidenceFilter).reporter.(*textReporter)
+	assert.Equal(t, os.Stdout, main.w, "reporters detect terminals on stdout itself")
+}
+
+func TestConfidenceFilter(t *testing.T) {
+	var buf bytes.Buffer
+	r := &confidenceFilter{reporter: newTextReporter(&buf, false), min: 0.8}
+	require.NoError(t, r.Report("pkg", []scopelint.Problem{
+		{Position: token.Position{Filename: "a.go", Line: 1, Column: 1}, Text: "sure", Confidence: 1},
+		{Position: token.Position{Filename: "a.go", Line: 2, Column: 1}, Text: "unsure", Confidence: 0.5},
+	}))
+	assert.Equal(t, "a.go:1:1: sure\n", buf.String())
+}
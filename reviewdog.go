@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"go/token"
+	"io"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// rdjsonlReporter reports each problem in a line of the Reviewdog Diagnostic Format (rdjsonl).
+// Edits of suggested fixes are reported as suggestions which reviewers can apply.
+type rdjsonlReporter struct {
+	enc         *json.Encoder
+	showIgnored bool
+}
+
+type rdDiagnostic struct {
+	Message          string              `json:"message"`
+	Location         rdLocation          `json:"location"`
+	Severity         string              `json:"severity"`
+	Source           rdSource            `json:"source"`
+	Code             rdCode              `json:"code"`
+	Suggestions      []rdSuggestion      `json:"suggestions,omitempty"`
+	RelatedLocations []rdRelatedLocation `json:"related_locations,omitempty"`
+}
+
+type rdLocation struct {
+	Path  string  `json:"path"`
+	Range rdRange `json:"range"`
+}
+
+type rdRange struct {
+	Start rdPosition  `json:"start"`
+	End   *rdPosition `json:"end,omitempty"`
+}
+
+type rdPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column,omitempty"`
+}
+
+type rdSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type rdCode struct {
+	Value string `json:"value"`
+	URL   string `json:"url,omitempty"`
+}
+
+type rdSuggestion struct {
+	Range rdRange `json:"range"`
+	Text  string  `json:"text"`
+}
+
+type rdRelatedLocation struct {
+	Message  string     `json:"message,omitempty"`
+	Location rdLocation `json:"location"`
+}
+
+func newRDJSONLReporter(w io.Writer, showIgnored bool) *rdjsonlReporter {
+	return &rdjsonlReporter{enc: json.NewEncoder(w), showIgnored: showIgnored}
+}
+
+func (r *rdjsonlReporter) Report(_ string, problems []scopelint.Problem) error {
+	for _, p := range problems {
+		if p.Ignored && !r.showIgnored {
+			continue
+		}
+		if err := r.enc.Encode(newRDDiagnostic(p)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReportError reports an error as a diagnostic without a location in files.
+func (r *rdjsonlReporter) ReportError(e lintError) error {
+	message := e.Message
+	if e.Package != "" {
+		message = e.Package + ": " + message
+	}
+	return r.enc.Encode(rdDiagnostic{
+		Message:  message,
+		Severity: "ERROR",
+		Source:   rdSource{Name: "scopelint", URL: "https://github.com/kyoh86/scopelint"},
+		Code:     rdCode{Value: e.Class},
+	})
+}
+
+func (r *rdjsonlReporter) Close() error { return nil }
+
+func newRDDiagnostic(p scopelint.Problem) rdDiagnostic {
+	path, _ := relativePath(p.Position.Filename)
+	diagnostic := rdDiagnostic{
+		Message:  p.Text,
+		Location: rdLocation{Path: path, Range: newRDRange(p.Position, p.End)},
+		Severity: rdSeverity(p),
+		Source:   rdSource{Name: "scopelint", URL: "https://github.com/kyoh86/scopelint"},
+		Code:     rdCode{Value: p.Category, URL: p.Link},
+	}
+	if p.Ignored {
+		diagnostic.Message += " (ignored)"
+	} else {
+		// Suggestions apply to the file of the diagnostic only.
+		for _, fix := range p.Fixes {
+			for _, edit := range fix.Edits {
+				if edit.Position.Filename != p.Position.Filename {
+					continue
+				}
+				diagnostic.Suggestions = append(diagnostic.Suggestions, rdSuggestion{
+					Range: newRDRange(edit.Position, edit.End),
+					Text:  edit.NewText,
+				})
+			}
+		}
+	}
+	for _, related := range p.Related {
+		path, _ := relativePath(related.Position.Filename)
+		diagnostic.RelatedLocations = append(diagnostic.RelatedLocations, rdRelatedLocation{
+			Message:  related.Message,
+			Location: rdLocation{Path: path, Range: newRDRange(related.Position, token.Position{})},
+		})
+	}
+	return diagnostic
+}
+
+func newRDRange(start, end token.Position) rdRange {
+	r := rdRange{Start: rdPosition{Line: start.Line, Column: start.Column}}
+	if end.Line > 0 {
+		r.End = &rdPosition{Line: end.Line, Column: end.Column}
+	}
+	return r
+}
+
+func rdSeverity(p scopelint.Problem) string {
+	if p.Ignored {
+		return "INFO"
+	}
+	switch problemSeverity(p) {
+	case severityWarning:
+		return "WARNING"
+	case severityInfo:
+		return "INFO"
+	}
+	return "ERROR"
+}
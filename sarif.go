@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"go/token"
+	"io"
+	"net/url"
+	"unicode/utf16"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// sarifReporter reports problems in SARIF 2.1.0.
+// All problems are buffered and written in a log on Close, sorted by position.
+type sarifReporter struct {
+	w        io.Writer
+	problems []scopelint.Problem
+	errors   []lintError
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	ColumnKind  string            `json:"columnKind"`
+	Invocations []sarifInvocation `json:"invocations,omitempty"`
+	Results     []sarifResult     `json:"results"`
+}
+
+// sarifInvocation is an invocation of scopelint, which is written only if errors are found.
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications"`
+}
+
+type sarifNotification struct {
+	Level      string                   `json:"level"`
+	Message    sarifMessage             `json:"message"`
+	Descriptor sarifReportingDescriptor `json:"descriptor"`
+	Properties *sarifErrorProperties    `json:"properties,omitempty"`
+}
+
+type sarifReportingDescriptor struct {
+	ID string `json:"id"`
+}
+
+type sarifErrorProperties struct {
+	Package string `json:"package"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	HelpURI              string             `json:"helpUri,omitempty"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string              `json:"ruleId"`
+	RuleIndex           int                 `json:"ruleIndex"`
+	Level               string              `json:"level"`
+	Message             sarifMessage        `json:"message"`
+	Locations           []sarifLocation     `json:"locations"`
+	RelatedLocations    []sarifLocation     `json:"relatedLocations,omitempty"`
+	Fixes               []sarifFix          `json:"fixes,omitempty"`
+	Suppressions        []sarifSuppression  `json:"suppressions,omitempty"`
+	PartialFingerprints map[string]string   `json:"partialFingerprints"`
+	Properties          *sarifPropertiesBag `json:"properties,omitempty"`
+}
+
+type sarifPropertiesBag struct {
+	Confidence float64 `json:"confidence"`
+}
+
+type sarifLocation struct {
+	ID               *int                  `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion  `json:"deletedRegion"`
+	InsertedContent sarifMessage `json:"insertedContent"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+func (r *sarifReporter) Report(_ string, problems []scopelint.Problem) error {
+	r.problems = append(r.problems, problems...)
+	return nil
+}
+
+func (r *sarifReporter) ReportError(e lintError) error {
+	r.errors = append(r.errors, e)
+	return nil
+}
+
+func (r *sarifReporter) Close() error {
+	rules := scopelint.Rules()
+	ruleIndex := map[string]int{}
+	driver := sarifDriver{
+		Name:           "scopelint",
+		Version:        version,
+		InformationURI: "https://github.com/kyoh86/scopelint",
+		Rules:          []sarifRule{},
+	}
+	for i, rule := range rules {
+		ruleIndex[rule.Category] = i
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   rule.Category,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			HelpURI:              rule.Link,
+			DefaultConfiguration: sarifConfiguration{Level: "error"},
+		})
+	}
+
+	scopelint.SortProblems(r.problems)
+	sources := sourceCache{}
+	results := []sarifResult{}
+	for _, p := range r.problems {
+		index, ok := ruleIndex[p.Category]
+		if !ok {
+			index = len(driver.Rules)
+			ruleIndex[p.Category] = index
+			driver.Rules = append(driver.Rules, sarifRule{
+				ID:                   p.Category,
+				ShortDescription:     sarifMessage{Text: p.Category},
+				DefaultConfiguration: sarifConfiguration{Level: "error"},
+			})
+		}
+		results = append(results, newSarifResult(p, index, sources))
+	}
+
+	run := sarifRun{Tool: sarifTool{Driver: driver}, ColumnKind: "utf16CodeUnits", Results: results}
+	if len(r.errors) > 0 {
+		invocation := sarifInvocation{}
+		for _, e := range r.errors {
+			notification := sarifNotification{
+				Level:      "error",
+				Message:    sarifMessage{Text: e.Message},
+				Descriptor: sarifReportingDescriptor{ID: e.Class},
+			}
+			if e.Package != "" {
+				notification.Properties = &sarifErrorProperties{Package: e.Package}
+			}
+			invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, notification)
+		}
+		run.Invocations = []sarifInvocation{invocation}
+	}
+
+	enc := json.NewEncoder(r.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// newSarifResult converts the problem to a result.
+// Columns are converted from bytes to UTF-16 code units with the source lines, as the columnKind of the run.
+func newSarifResult(p scopelint.Problem, ruleIndex int, sources sourceCache) sarifResult {
+	result := sarifResult{
+		RuleID:    p.Category,
+		RuleIndex: ruleIndex,
+		Level:     sarifLevel(problemSeverity(p)),
+		Message:   sarifMessage{Text: p.Text},
+		Locations: []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: sarifURI(p.Position.Filename)},
+				Region:           sarifRegion{StartLine: p.Position.Line, StartColumn: sarifColumn(sources, p.Position)},
+			},
+		}},
+		PartialFingerprints: map[string]string{"scopelint/v1": p.Fingerprint()},
+	}
+	if p.Confidence < 1 {
+		result.Properties = &sarifPropertiesBag{Confidence: p.Confidence}
+	}
+	for i, related := range p.Related {
+		id := i + 1
+		result.RelatedLocations = append(result.RelatedLocations, sarifLocation{
+			ID: &id,
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: sarifURI(related.Position.Filename)},
+				Region:           sarifRegion{StartLine: related.Position.Line, StartColumn: sarifColumn(sources, related.Position)},
+			},
+			Message: &sarifMessage{Text: related.Message},
+		})
+	}
+	for _, fix := range p.Fixes {
+		changes := map[string]*sarifArtifactChange{}
+		var uris []string
+		for _, edit := range fix.Edits {
+			uri := sarifURI(edit.Position.Filename)
+			change, ok := changes[uri]
+			if !ok {
+				change = &sarifArtifactChange{ArtifactLocation: sarifArtifactLocation{URI: uri}}
+				changes[uri] = change
+				uris = append(uris, uri)
+			}
+			change.Replacements = append(change.Replacements, sarifReplacement{
+				DeletedRegion:   sarifEditRegion(sources, edit.Position, edit.End),
+				InsertedContent: sarifMessage{Text: edit.NewText},
+			})
+		}
+		sarifFix := sarifFix{Description: sarifMessage{Text: fix.Message}}
+		for _, uri := range uris {
+			sarifFix.ArtifactChanges = append(sarifFix.ArtifactChanges, *changes[uri])
+		}
+		result.Fixes = append(result.Fixes, sarifFix)
+	}
+	if p.Ignored {
+		suppression := sarifSuppression{Kind: "inSource"}
+		if p.Directive != nil {
+			suppression.Justification = p.Directive.Justification
+		}
+		result.Suppressions = []sarifSuppression{suppression}
+	}
+	return result
+}
+
+func sarifEditRegion(sources sourceCache, start, end token.Position) sarifRegion {
+	return sarifRegion{
+		StartLine:   start.Line,
+		StartColumn: sarifColumn(sources, start),
+		EndLine:     end.Line,
+		EndColumn:   sarifColumn(sources, end),
+	}
+}
+
+// sarifColumn returns the column of the position in UTF-16 code units.
+// If the source line cannot be read, it returns the column in bytes.
+func sarifColumn(sources sourceCache, pos token.Position) int {
+	lines := sources.lines(pos.Filename)
+	if pos.Column <= 1 || pos.Line < 1 || pos.Line > len(lines) || pos.Column-1 > len(lines[pos.Line-1]) {
+		return pos.Column
+	}
+	return len(utf16.Encode([]rune(lines[pos.Line-1][:pos.Column-1]))) + 1
+}
+
+func sarifLevel(severity string) string {
+	switch severity {
+	case severityWarning:
+		return "warning"
+	case severityInfo:
+		return "note"
+	}
+	return "error"
+}
+
+// sarifURI returns a relative URI of the file from the working directory if possible,
+// or an absolute file URI.
+func sarifURI(filename string) string {
+	rel, ok := relativePath(filename)
+	if ok {
+		return (&url.URL{Path: rel}).String()
+	}
+	return (&url.URL{Scheme: "file", Path: rel}).String()
+}
@@ -0,0 +1,182 @@
+package scopelint
+
+import (
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// knownOS and knownArch are the values of GOOS and GOARCH known by the go command.
+var (
+	knownOS = []string{
+		"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js",
+		"linux", "nacl", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos",
+	}
+	knownArch = []string{
+		"386", "amd64", "amd64p32", "arm", "armbe", "arm64", "arm64be", "loong64", "mips", "mipsle",
+		"mips64", "mips64le", "mips64p32", "mips64p32le", "ppc", "ppc64", "ppc64le", "riscv", "riscv64",
+		"s390", "s390x", "sparc", "sparc64", "wasm",
+	}
+)
+
+// maxBuildTagCombinations is the number of custom build tags over which
+// not all combinations of them are tried with --all-build-contexts.
+const maxBuildTagCombinations = 8
+
+// noGoFiles reports whether the error of importing a package is due to no Go source files to lint.
+// With AllBuildContexts, files excluded by build constraints are linted too.
+func (r *resolver) noGoFiles(pkg *build.Package, err error) bool {
+	if _, noGo := err.(*build.NoGoError); !noGo {
+		return false
+	}
+	return !r.allBuildContexts || pkg == nil || len(pkg.IgnoredGoFiles) == 0
+}
+
+// isPackage reports whether the directory has Go source files to lint.
+func (r *resolver) isPackage(dir string) bool {
+	pkg, err := r.ctx.ImportDir(dir, 0)
+	return !r.noGoFiles(pkg, err)
+}
+
+// namedContext is a build context with a name like "linux/amd64,integration".
+type namedContext struct {
+	name string
+	ctx  build.Context
+}
+
+// buildContexts returns every combination of the GOOS, GOARCH and custom build tags
+// which the files of the package are constrained by.
+// The GOOS and GOARCH of the build context are always included.
+// The tag "ignore" is left out, since it conventionally marks files out of the package,
+// and cgo is enabled or disabled following the build context like the other built-in tags.
+func (r *resolver) buildContexts(pkg *build.Package) []namedContext {
+	goos := map[string]bool{r.ctx.GOOS: true}
+	goarch := map[string]bool{r.ctx.GOARCH: true}
+	var tags []string
+	for _, tag := range pkg.AllTags {
+		switch {
+		case contains(knownOS, tag):
+			goos[tag] = true
+		case contains(knownArch, tag):
+			goarch[tag] = true
+		case tag == "ignore" || tag == "unix" || tag == "cgo" || tag == "gc" || tag == "gccgo" || strings.HasPrefix(tag, "go1."):
+		case contains(r.ctx.BuildTags, tag):
+		default:
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+
+	var tagSets [][]string
+	if len(tags) <= maxBuildTagCombinations {
+		for bits := 0; bits < 1<<uint(len(tags)); bits++ {
+			var set []string
+			for i, tag := range tags {
+				if bits&(1<<uint(i)) != 0 {
+					set = append(set, tag)
+				}
+			}
+			tagSets = append(tagSets, set)
+		}
+	} else {
+		r.logf("warning: %s: too many build tags to combine; trying each of them alone and all of them", pkg.Dir)
+		tagSets = append(tagSets, nil)
+		for _, tag := range tags {
+			tagSets = append(tagSets, []string{tag})
+		}
+		tagSets = append(tagSets, tags)
+	}
+
+	var contexts []namedContext
+	for _, targetOS := range sortedKeys(goos) {
+		for _, targetArch := range sortedKeys(goarch) {
+			for _, set := range tagSets {
+				ctx := *r.ctx
+				ctx.GOOS, ctx.GOARCH = targetOS, targetArch
+				ctx.BuildTags = append(append([]string{}, r.ctx.BuildTags...), set...)
+				name := targetOS + "/" + targetArch
+				if len(set) > 0 {
+					name += "," + strings.Join(set, ",")
+				}
+				contexts = append(contexts, namedContext{name: name, ctx: ctx})
+			}
+		}
+	}
+	return contexts
+}
+
+// buildContextFiles returns the Go files of the package built in any of the build contexts,
+// with the names of the contexts each file is built in.
+//
+// scopelint checks each file on its own, so a file has the same problems in every context
+// it is built in: the files are linted once, and the problems are marked with the contexts.
+func (r *resolver) buildContextFiles(pkg *build.Package) (files []string, contexts map[string][]string) {
+	var candidates []string
+	for _, list := range [][]string{pkg.GoFiles, pkg.CgoFiles, pkg.TestGoFiles, pkg.XTestGoFiles, pkg.IgnoredGoFiles} {
+		for _, name := range list {
+			// Files importing "C" are not built without cgo.
+			if !r.ctx.CgoEnabled && strings.HasSuffix(name, ".go") && r.importsC(filepath.Join(pkg.Dir, name)) {
+				continue
+			}
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+
+	contexts = map[string][]string{}
+	for _, nc := range r.buildContexts(pkg) {
+		for _, name := range candidates {
+			if !strings.HasSuffix(name, ".go") || (!r.tests && strings.HasSuffix(name, "_test.go")) {
+				continue
+			}
+			if match, err := nc.ctx.MatchFile(pkg.Dir, name); err != nil || !match {
+				continue
+			}
+			if _, ok := contexts[name]; !ok {
+				files = append(files, name)
+			}
+			contexts[name] = append(contexts[name], nc.name)
+		}
+	}
+	sort.Strings(files)
+	return files, contexts
+}
+
+// importsC reports whether the file imports "C".
+func (r *resolver) importsC(filename string) bool {
+	src, err := r.readFile(filename)
+	if err != nil {
+		return false
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.ImportsOnly)
+	if err != nil {
+		return false
+	}
+	for _, spec := range file.Imports {
+		if spec.Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
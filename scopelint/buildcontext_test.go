@@ -0,0 +1,76 @@
+package scopelint
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildContextFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scopelint-build")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"plain.go":     "package p\n",
+		"la.go":        "//go:build linux && arm64\n\npackage p\n",
+		"w_windows.go": "package p\n",
+		"i_test.go":    "//go:build integration\n\npackage p\n",
+		"gen.go":       "//go:build ignore\n\npackage main\n",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	ctx := build.Default
+	ctx.GOOS, ctx.GOARCH = "linux", "amd64"
+	r := (&BuildLoader{Context: &ctx, Tests: true, AllBuildContexts: true}).resolver(nil)
+
+	pkg, err := ctx.ImportDir(dir, 0)
+	require.NoError(t, err)
+
+	files, contexts := r.buildContextFiles(pkg)
+	assert.Equal(t, []string{"i_test.go", "la.go", "plain.go", "w_windows.go"}, files)
+	assert.Equal(t, []string{"linux/arm64", "linux/arm64,integration"}, contexts["la.go"])
+	assert.Equal(t, []string{"linux/amd64,integration", "linux/arm64,integration", "windows/amd64,integration", "windows/arm64,integration"}, contexts["i_test.go"])
+	assert.Len(t, contexts["plain.go"], 8)
+
+	r.tests = false
+	files, _ = r.buildContextFiles(pkg)
+	assert.Equal(t, []string{"la.go", "plain.go", "w_windows.go"}, files)
+}
+
+func TestBuildContextFilesCgo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scopelint-build")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"plain.go":  "package p\n",
+		"c.go":      "package p\n\nimport \"C\"\n",
+		"tagged.go": "//go:build cgo\n\npackage p\n",
+		"pure.go":   "//go:build !cgo\n\npackage p\n",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	for _, cgo := range []bool{true, false} {
+		ctx := build.Default
+		ctx.GOOS, ctx.GOARCH, ctx.CgoEnabled = "linux", "amd64", cgo
+		r := (&BuildLoader{Context: &ctx, AllBuildContexts: true}).resolver(nil)
+		pkg, err := ctx.ImportDir(dir, 0)
+		require.NoError(t, err)
+
+		files, contexts := r.buildContextFiles(pkg)
+		assert.Equal(t, []string{"linux/amd64"}, contexts["plain.go"], "cgo is not combined as a custom tag")
+		if cgo {
+			assert.Equal(t, []string{"c.go", "plain.go", "tagged.go"}, files)
+		} else {
+			assert.Equal(t, []string{"plain.go", "pure.go"}, files, "files importing C are not built without cgo")
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package scopelint
+
+import (
+	"context"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BuildLoader loads packages with go/build in GOPATH mode.
+//
+// The patterns are import paths, directories beginning with "./", "../" or "/",
+// and patterns of them including "...", like the go command.
+// "all" matches all packages in GOPATH and GOROOT, and "std" and "cmd" match the standard packages and commands.
+type BuildLoader struct {
+	// Context is the build context to find and read files. If it is nil, build.Default is used.
+	Context *build.Context
+
+	// Tests loads the `*_test.go` files too.
+	Tests bool
+
+	// AllBuildContexts loads files for every combination of build constraints in each package,
+	// instead of the files built in Context.
+	AllBuildContexts bool
+
+	// Logf is called with warnings like patterns matching no packages. It may be nil.
+	Logf func(format string, args ...interface{})
+}
+
+// Load loads the packages matching the patterns.
+func (l *BuildLoader) Load(ctx context.Context, patterns ...string) ([]*LoadedPackage, error) {
+	return l.resolver(nil).load(ctx, patterns)
+}
+
+// Resolve resolves the packages matching the patterns, whose files are read by the returned functions (see LazyLoader).
+func (l *BuildLoader) Resolve(ctx context.Context, patterns ...string) ([]func() *LoadedPackage, error) {
+	return l.resolver(nil).resolve(ctx, patterns)
+}
+
+// ImportPaths returns the import paths and directories of the packages matching the patterns without loading them,
+// in the order Load loads them in. The order is stable for the same patterns and files,
+// so it can be partitioned into shards (see Shard).
+func (l *BuildLoader) ImportPaths(patterns ...string) ([]string, error) {
+	return l.resolver(nil).packages(patterns), nil
+}
+
+func (l *BuildLoader) resolver(w *workspace) *resolver {
+	r := &resolver{
+		ctx:              l.Context,
+		workspace:        w,
+		tests:            l.Tests,
+		allBuildContexts: l.AllBuildContexts,
+		logf:             l.Logf,
+	}
+	if r.ctx == nil {
+		r.ctx = &build.Default
+	}
+	if r.logf == nil {
+		r.logf = func(string, ...interface{}) {}
+	}
+	return r
+}
+
+// ModuleLoader loads packages like BuildLoader, but resolves import paths in the Go workspace
+// of the working directory: the modules used in the go.work file, or the module containing the working directory.
+// Packages are resolved offline without the go command; those out of the workspace and its local replacements
+// are found in GOROOT. If the working directory is out of any module, it works like BuildLoader.
+//
+// The pattern "all" matches the packages in the workspace.
+type ModuleLoader struct {
+	BuildLoader
+
+	// Workspace is the go.work file to use: WorkspaceAuto (or empty), WorkspaceOff or its path.
+	// WorkspaceAuto follows the GOWORK environment variable, and finds go.work upward from the working directory.
+	Workspace string
+}
+
+// Load loads the packages matching the patterns.
+func (l *ModuleLoader) Load(ctx context.Context, patterns ...string) ([]*LoadedPackage, error) {
+	w, err := findWorkspace(l.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	return l.resolver(w).load(ctx, patterns)
+}
+
+// Resolve resolves the packages matching the patterns, whose files are read by the returned functions (see LazyLoader).
+func (l *ModuleLoader) Resolve(ctx context.Context, patterns ...string) ([]func() *LoadedPackage, error) {
+	w, err := findWorkspace(l.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	return l.resolver(w).resolve(ctx, patterns)
+}
+
+// ImportPaths returns the import paths and directories of the packages matching the patterns without loading them,
+// like BuildLoader.ImportPaths.
+func (l *ModuleLoader) ImportPaths(patterns ...string) ([]string, error) {
+	w, err := findWorkspace(l.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	return l.resolver(w).packages(patterns), nil
+}
+
+// resolver resolves patterns to packages with a build context, in a workspace if any.
+type resolver struct {
+	ctx              *build.Context
+	workspace        *workspace // nil in GOPATH mode
+	tests            bool
+	allBuildContexts bool
+	logf             func(format string, args ...interface{})
+}
+
+func (r *resolver) load(ctx context.Context, patterns []string) ([]*LoadedPackage, error) {
+	loads, err := r.resolve(ctx, patterns)
+	if err != nil {
+		return nil, err
+	}
+	pkgs := make([]*LoadedPackage, 0, len(loads))
+	for _, load := range loads {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, load())
+	}
+	return pkgs, nil
+}
+
+// resolve imports the packages matching the patterns, and returns the functions reading their files.
+// Packages without Go source files to lint and packages in the same directories as others are left out.
+func (r *resolver) resolve(ctx context.Context, patterns []string) ([]func() *LoadedPackage, error) {
+	var loads []func() *LoadedPackage
+	seen := map[string]bool{}
+	for _, name := range r.packages(patterns) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var pkg *build.Package
+		var err error
+		if isLocalPattern(name) {
+			pkg, err = r.importDir(name)
+		} else {
+			pkg, err = r.importPackage(name)
+		}
+		if err != nil {
+			if _, nogo := err.(*build.NoGoError); !nogo {
+				loaded := &LoadedPackage{Name: name, Err: err}
+				if pkg != nil {
+					loaded.Dir = pkg.Dir
+				}
+				loads = append(loads, func() *LoadedPackage { return loaded })
+				continue
+			}
+			if r.noGoFiles(pkg, err) {
+				// Don't complain if the failure is due to no Go source files.
+				continue
+			}
+		}
+		if seen[pkg.Dir] {
+			continue
+		}
+		seen[pkg.Dir] = true
+		imported := pkg
+		loads = append(loads, func() *LoadedPackage { return r.loadPackage(imported) })
+	}
+	return loads, nil
+}
+
+// packages expands the patterns to the import paths and directories of packages without duplicates.
+// They are in the order of the patterns, and the ones matching a pattern with "..." are in the order of their directories.
+func (r *resolver) packages(patterns []string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		var matched []string
+		switch {
+		case !isLocalPattern(pattern):
+			matched = r.importPaths([]string{pattern})
+		case strings.Contains(pattern, "..."):
+			matched = r.allPackagesInFS(pattern)
+		default:
+			matched = []string{pattern}
+		}
+		for _, name := range matched {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// isLocalPattern reports whether the pattern is of directories rather than import paths.
+func isLocalPattern(pattern string) bool {
+	return build.IsLocalImport(pattern) || filepath.IsAbs(pattern)
+}
+
+// loadPackage reads the files of the imported package.
+// It is safe for concurrent use.
+func (r *resolver) loadPackage(pkg *build.Package) *LoadedPackage {
+	loaded := &LoadedPackage{
+		Name:  pkg.ImportPath,
+		Dir:   pkg.Dir,
+		Files: map[string][]byte{},
+		Build: moduleBuildInfo(pkg.Dir),
+	}
+	if loaded.Name == "" || loaded.Name == "." {
+		loaded.Name = pkg.Dir
+	}
+	loaded.Build.GOOS = r.ctx.GOOS
+	loaded.Build.GOARCH = r.ctx.GOARCH
+	loaded.Build.BuildTags = r.ctx.BuildTags
+
+	var files []string
+	var contexts map[string][]string
+	if r.allBuildContexts {
+		files, contexts = r.buildContextFiles(pkg)
+		loaded.Build.Contexts = map[string][]string{}
+	} else {
+		files = append(files, pkg.GoFiles...)
+		files = append(files, pkg.CgoFiles...)
+		if r.tests {
+			files = append(files, pkg.TestGoFiles...)
+			files = append(files, pkg.XTestGoFiles...)
+		}
+	}
+	for _, f := range files {
+		filename := f
+		if pkg.Dir != "." {
+			filename = filepath.Join(pkg.Dir, f)
+		}
+		if contexts != nil {
+			loaded.Build.Contexts[filename] = contexts[f]
+		}
+		src, err := r.readFile(filename)
+		if err != nil {
+			loaded.Err = err
+			continue
+		}
+		loaded.Files[filename] = src
+	}
+	return loaded
+}
+
+// readFile reads the file with the build context.
+func (r *resolver) readFile(filename string) ([]byte, error) {
+	if r.ctx.OpenFile == nil {
+		return ioutil.ReadFile(filename)
+	}
+	f, err := r.ctx.OpenFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// importPackage imports the package of the import path.
+// In a workspace, packages are found in the workspace, the local replacements and GOROOT without the go command.
+func (r *resolver) importPackage(importPath string) (*build.Package, error) {
+	if r.workspace == nil || build.IsLocalImport(importPath) {
+		return r.ctx.Import(importPath, ".", 0)
+	}
+	dir, ok := r.workspace.dir(importPath)
+	if !ok {
+		dir = filepath.Join(gorootSrc, filepath.FromSlash(importPath))
+		if !isDirExists(dir) {
+			return nil, fmt.Errorf("cannot find package %q in the workspace or its local replacements", importPath)
+		}
+	}
+	pkg, err := r.ctx.ImportDir(relativeDir(dir), 0)
+	if pkg != nil {
+		pkg.ImportPath = importPath
+	}
+	return pkg, err
+}
+
+// importDir imports the package in the directory, with the import path in its module if any.
+func (r *resolver) importDir(dir string) (*build.Package, error) {
+	pkg, err := r.ctx.ImportDir(dir, 0)
+	if pkg != nil && (pkg.ImportPath == "" || pkg.ImportPath == ".") {
+		if mod, merr := FindModule(dir); merr == nil && mod != nil {
+			if importPath, ok := mod.importPath(dir); ok {
+				pkg.ImportPath = importPath
+			}
+		}
+	}
+	return pkg, err
+}
+
+// relativeDir returns the directory relative to the working directory if it is in it.
+func relativeDir(dir string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return dir
+	}
+	rel, err := filepath.Rel(wd, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return dir
+	}
+	return rel
+}
+
+func isDirExists(filename string) bool {
+	fi, err := os.Stat(filename)
+	return err == nil && fi.IsDir()
+}
+
+func isFileExists(filename string) bool {
+	fi, err := os.Stat(filename)
+	return err == nil && !fi.IsDir()
+}
@@ -0,0 +1,156 @@
+package scopelint
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+	"time"
+)
+
+// DirectiveScope is the extent of the source code which an ignore directive applies to.
+type DirectiveScope string
+
+const (
+	// ScopeLine is the scope of a directive put on the end of a line of code.
+	ScopeLine DirectiveScope = "line"
+	// ScopeBlock is the scope of a directive put on the beginning of a line before a block.
+	ScopeBlock DirectiveScope = "block"
+	// ScopeFile is the scope of a directive put before the package clause.
+	ScopeFile DirectiveScope = "file"
+)
+
+const (
+	ignoreOption  = "ignore"
+	expiresOption = "expires"
+
+	expiresLayout = "2006-01-02"
+)
+
+// now is replaced in tests to check the expiry of directives.
+var now = time.Now
+
+// Directive represents an ignore option comment like `//scopelint:ignore`.
+//
+// The directive can be narrowed to some categories of problem with `ignore=<category>`,
+// and can be expired with `expires=<yyyy-mm-dd>`:
+//
+//	//scopelint:ignore=range-scope,expires=2019-12-31 // any comment
+type Directive struct {
+	Position      token.Position `json:"-"`             // position of the comment
+	Scope         DirectiveScope `json:"scope"`         // extent of the source code which the directive applies to
+	Rules         []string       `json:"rules"`         // categories muted by the directive; empty means all of them
+	Justification string         `json:"justification"` // the comment explaining why the directive is being used
+	Expires       time.Time      `json:"-"`             // the date the directive expires on; zero means it never expires
+	Hidden        int            `json:"hidden"`        // number of problems the directive hides
+}
+
+// Expired reports whether the directive has been expired at t.
+func (d *Directive) Expired(t time.Time) bool {
+	return !d.Expires.IsZero() && !t.Before(d.Expires)
+}
+
+// Mutes reports whether the directive mutes the category of problem.
+func (d *Directive) Mutes(category string) bool {
+	if len(d.Rules) == 0 {
+		return true
+	}
+	for _, rule := range d.Rules {
+		if rule == category {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDirective parses an ignore option comment.
+// If the comment has no ignore option, it returns false.
+func parseDirective(comment string) (directive *Directive, ok bool) {
+	directive = &Directive{}
+	foreachOptionComment(comment, func(opt string) bool {
+		name, value := opt, ""
+		if i := strings.Index(opt, "="); i >= 0 {
+			name, value = strings.TrimSpace(opt[:i]), strings.TrimSpace(opt[i+1:])
+		}
+		switch name {
+		case ignoreOption:
+			ok = true
+			if value != "" {
+				directive.Rules = append(directive.Rules, value)
+			}
+		case expiresOption:
+			if expires, err := time.ParseInLocation(expiresLayout, value, time.Local); err == nil {
+				directive.Expires = expires
+			}
+		}
+		return true
+	})
+	if !ok {
+		return nil, false
+	}
+
+	comment = strings.TrimSuffix(strings.TrimPrefix(comment, "/*"), "*/")
+	var justification []string
+	for _, sentence := range strings.Split(comment, "//") {
+		sentence = strings.TrimSpace(sentence)
+		if sentence == "" || strings.HasPrefix(sentence, optionPrefix) {
+			continue
+		}
+		justification = append(justification, sentence)
+	}
+	directive.Justification = strings.Join(justification, " // ")
+	return directive, true
+}
+
+// parseDirectives finds ignore directives in the comments of the file.
+func (f *File) parseDirectives() {
+	f.Directives = map[*ast.Comment]*Directive{}
+	for _, cg := range f.ASTFile.Comments {
+		for _, com := range cg.List {
+			directive, ok := parseDirective(com.Text)
+			if !ok {
+				continue
+			}
+			directive.Position = f.FileSet.Position(com.Pos())
+			switch {
+			case com.End() < f.ASTFile.Package:
+				directive.Scope = ScopeFile
+			case strings.TrimSpace(srcLine(f.Source, directive.Position)[:directive.Position.Column-1]) != "":
+				directive.Scope = ScopeLine
+			default:
+				directive.Scope = ScopeBlock
+			}
+			f.Directives[com] = directive
+			f.Package.Directives = append(f.Package.Directives, directive)
+		}
+	}
+}
+
+// suppressor returns the directive which mutes the category of problem in the directives.
+// If no directive mutes it, it returns nil.
+func suppressor(directives []*Directive, category string) *Directive {
+	t := now()
+	for i := len(directives) - 1; i >= 0; i-- {
+		directive := directives[i]
+		if directive.Mutes(category) && !directive.Expired(t) {
+			return directive
+		}
+	}
+	return nil
+}
+
+type directivesByPosition []*Directive
+
+func (d directivesByPosition) Len() int      { return len(d) }
+func (d directivesByPosition) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+
+func (d directivesByPosition) Less(i, j int) bool {
+	pi, pj := d[i].Position, d[j].Position
+
+	if pi.Filename != pj.Filename {
+		return pi.Filename < pj.Filename
+	}
+	if pi.Line != pj.Line {
+		return pi.Line < pj.Line
+	}
+	return pi.Column < pj.Column
+}
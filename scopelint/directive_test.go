@@ -0,0 +1,91 @@
+package scopelint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDirective(t *testing.T) {
+	t.Run("normal comment", func(t *testing.T) {
+		_, ok := parseDirective("// comment")
+		assert.False(t, ok)
+	})
+
+	t.Run("other option", func(t *testing.T) {
+		_, ok := parseDirective("//scopelint:other")
+		assert.False(t, ok)
+	})
+
+	t.Run("ignore all", func(t *testing.T) {
+		d, ok := parseDirective("//scopelint:ignore")
+		require.True(t, ok)
+		assert.Empty(t, d.Rules)
+		assert.Empty(t, d.Justification)
+		assert.True(t, d.Expires.IsZero())
+		assert.True(t, d.Mutes("range-scope"))
+	})
+
+	t.Run("ignore rules", func(t *testing.T) {
+		d, ok := parseDirective("//scopelint:ignore=range-scope,ignore=other")
+		require.True(t, ok)
+		assert.Equal(t, []string{"range-scope", "other"}, d.Rules)
+		assert.True(t, d.Mutes("range-scope"))
+		assert.False(t, d.Mutes("another"))
+	})
+
+	t.Run("justification", func(t *testing.T) {
+		d, ok := parseDirective("//scopelint:ignore // any comment")
+		require.True(t, ok)
+		assert.Equal(t, "any comment", d.Justification)
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		d, ok := parseDirective("//scopelint:ignore,expires=2019-12-31")
+		require.True(t, ok)
+		assert.Equal(t, time.Date(2019, 12, 31, 0, 0, 0, 0, time.Local), d.Expires)
+		assert.False(t, d.Expired(time.Date(2019, 12, 30, 0, 0, 0, 0, time.Local)))
+		assert.True(t, d.Expired(time.Date(2019, 12, 31, 0, 0, 0, 0, time.Local)))
+	})
+}
+
+func TestDirectives(t *testing.T) {
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return time.Date(2019, 6, 1, 0, 0, 0, 0, time.Local) }
+
+	l := new(Linter)
+	directives, err := l.Directives(map[string][]byte{"mypkg/mypkg.go": []byte(`//scopelint:ignore=other // not for range-scope
+package main
+
+import "testing"
+
+func TestSomething(t *testing.T) {
+	for _, tc := range []struct {
+		expected    string
+	}{} {
+		t.Run("sub", func(t *testing.T) {
+			t.Log(tc.expected) //scopelint:ignore,expires=2019-01-01 // expired
+			//scopelint:ignore=range-scope // compare expected and result
+			if "result" != tc.expected {
+				t.Fatal(tc.expected)
+			}
+		})
+	}
+}`)})
+	require.NoError(t, err)
+	if assert.Len(t, directives, 3) {
+		assert.Equal(t, ScopeFile, directives[0].Scope)
+		assert.Equal(t, "not for range-scope", directives[0].Justification)
+		assert.Equal(t, 0, directives[0].Hidden)
+
+		assert.Equal(t, ScopeLine, directives[1].Scope)
+		assert.Equal(t, 11, directives[1].Position.Line)
+		assert.Equal(t, 0, directives[1].Hidden, "expired directive hides nothing")
+
+		assert.Equal(t, ScopeBlock, directives[2].Scope)
+		assert.Equal(t, []string{"range-scope"}, directives[2].Rules)
+		assert.Equal(t, 2, directives[2].Hidden)
+	}
+}
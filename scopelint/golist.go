@@ -0,0 +1,157 @@
+package scopelint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GoListLoader loads packages with `go list -json`.
+// The patterns are the ones of the go command, and packages are resolved by the go command itself,
+// so it follows the modules, the workspace and the build flags exactly at the cost of running it.
+type GoListLoader struct {
+	// Dir is the working directory of the go command. If it is empty, the current directory is used.
+	Dir string
+
+	// Env is the environment of the go command. If it is nil, the current environment is used.
+	Env []string
+
+	// BuildFlags are passed to the go command, like "-tags=integration".
+	BuildFlags []string
+
+	// Tests loads the `*_test.go` files too.
+	Tests bool
+}
+
+// goListPackage is a package in the output of `go list -json`.
+type goListPackage struct {
+	Dir          string
+	ImportPath   string
+	GoFiles      []string
+	CgoFiles     []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	Module       *struct {
+		Path      string
+		Dir       string
+		GoMod     string
+		GoVersion string
+	}
+	Error *struct {
+		Err string
+	}
+}
+
+// Load loads the packages matching the patterns.
+func (l *GoListLoader) Load(ctx context.Context, patterns ...string) ([]*LoadedPackage, error) {
+	env, err := l.goEnv(ctx, "GOOS", "GOARCH")
+	if err != nil {
+		return nil, err
+	}
+	args := append(append([]string{"list", "-e", "-json"}, l.BuildFlags...), patterns...)
+	out, err := l.goCommand(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []*LoadedPackage
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var listed goListPackage
+		if err := dec.Decode(&listed); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("go list: %v", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, l.loadPackage(&listed, env))
+	}
+	return pkgs, nil
+}
+
+func (l *GoListLoader) loadPackage(listed *goListPackage, env []string) *LoadedPackage {
+	pkg := &LoadedPackage{
+		Name:  listed.ImportPath,
+		Dir:   listed.Dir,
+		Files: map[string][]byte{},
+		Build: BuildInfo{GOOS: env[0], GOARCH: env[1], BuildTags: buildTags(l.BuildFlags)},
+	}
+	if m := listed.Module; m != nil {
+		pkg.Build.Module = &Module{Path: m.Path, Dir: m.Dir, GoMod: m.GoMod, GoVersion: m.GoVersion}
+		pkg.Build.GoVersion = m.GoVersion
+	}
+	if listed.Error != nil {
+		pkg.Err = errors.New(listed.Error.Err)
+		return pkg
+	}
+
+	files := append(append([]string{}, listed.GoFiles...), listed.CgoFiles...)
+	if l.Tests {
+		files = append(append(files, listed.TestGoFiles...), listed.XTestGoFiles...)
+	}
+	for _, f := range files {
+		filename := filepath.Join(listed.Dir, f)
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			pkg.Err = err
+			continue
+		}
+		pkg.Files[filename] = src
+	}
+	return pkg
+}
+
+// goEnv returns the values of the environment variables for the go command.
+func (l *GoListLoader) goEnv(ctx context.Context, names ...string) ([]string, error) {
+	out, err := l.goCommand(ctx, append([]string{"env"}, names...)...)
+	if err != nil {
+		return nil, err
+	}
+	values := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(values) != len(names) {
+		return nil, fmt.Errorf("go env: unexpected output %q", out)
+	}
+	return values, nil
+}
+
+func (l *GoListLoader) goCommand(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = l.Dir
+	cmd.Env = l.Env
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("go %s: %v: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("go %s: %v", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// buildTags returns the build tags in the -tags flag of the build flags.
+func buildTags(flags []string) []string {
+	for i, flag := range flags {
+		var tags string
+		switch {
+		case strings.HasPrefix(flag, "-tags="), strings.HasPrefix(flag, "--tags="):
+			tags = flag[strings.Index(flag, "=")+1:]
+		case (flag == "-tags" || flag == "--tags") && i+1 < len(flags):
+			tags = flags[i+1]
+		default:
+			continue
+		}
+		return strings.FieldsFunc(tags, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+	return nil
+}
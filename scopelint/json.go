@@ -0,0 +1,163 @@
+package scopelint
+
+import (
+	"encoding/json"
+	"go/token"
+	"time"
+)
+
+// SchemaVersion is the version of the JSON representation of Problem.
+// It will be incremented on incompatible changes.
+const SchemaVersion = 1
+
+// jsonPosition is the JSON representation of token.Position.
+type jsonPosition struct {
+	Filename string `json:"filename"`
+	Offset   int    `json:"offset"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+func newJSONPosition(pos token.Position) jsonPosition {
+	return jsonPosition{
+		Filename: pos.Filename,
+		Offset:   pos.Offset,
+		Line:     pos.Line,
+		Column:   pos.Column,
+	}
+}
+
+func (p jsonPosition) position() token.Position {
+	return token.Position{
+		Filename: p.Filename,
+		Offset:   p.Offset,
+		Line:     p.Line,
+		Column:   p.Column,
+	}
+}
+
+type (
+	problemFields         Problem
+	relatedLocationFields RelatedLocation
+	editFields            Edit
+	directiveFields       Directive
+)
+
+type problemJSON struct {
+	jsonPosition
+	End jsonPosition `json:"end"`
+	*problemFields
+	Fingerprint string `json:"fingerprint"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(problemJSON{
+		jsonPosition:  newJSONPosition(p.Position),
+		End:           newJSONPosition(p.End),
+		problemFields: (*problemFields)(&p),
+		Fingerprint:   p.Fingerprint(),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *Problem) UnmarshalJSON(raw []byte) error {
+	v := problemJSON{problemFields: (*problemFields)(p)}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	p.Position = v.jsonPosition.position()
+	p.End = v.End.position()
+	return nil
+}
+
+type relatedLocationJSON struct {
+	jsonPosition
+	*relatedLocationFields
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r RelatedLocation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(relatedLocationJSON{
+		jsonPosition:          newJSONPosition(r.Position),
+		relatedLocationFields: (*relatedLocationFields)(&r),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *RelatedLocation) UnmarshalJSON(raw []byte) error {
+	v := relatedLocationJSON{relatedLocationFields: (*relatedLocationFields)(r)}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	r.Position = v.jsonPosition.position()
+	return nil
+}
+
+type editJSON struct {
+	jsonPosition
+	End jsonPosition `json:"end"`
+	*editFields
+}
+
+// MarshalJSON implements json.Marshaler.
+func (e Edit) MarshalJSON() ([]byte, error) {
+	return json.Marshal(editJSON{
+		jsonPosition: newJSONPosition(e.Position),
+		End:          newJSONPosition(e.End),
+		editFields:   (*editFields)(&e),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *Edit) UnmarshalJSON(raw []byte) error {
+	v := editJSON{editFields: (*editFields)(e)}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	e.Position = v.jsonPosition.position()
+	e.End = v.End.position()
+	return nil
+}
+
+type directiveJSON struct {
+	jsonPosition
+	*directiveFields
+	Expires string `json:"expires,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Directive) MarshalJSON() ([]byte, error) {
+	v := directiveJSON{
+		jsonPosition:    newJSONPosition(d.Position),
+		directiveFields: (*directiveFields)(&d),
+	}
+	if v.Rules == nil {
+		v.Rules = []string{}
+	}
+	if !d.Expires.IsZero() {
+		v.Expires = d.Expires.Format(expiresLayout)
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Directive) UnmarshalJSON(raw []byte) error {
+	v := directiveJSON{directiveFields: (*directiveFields)(d)}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	d.Position = v.jsonPosition.position()
+	if len(d.Rules) == 0 {
+		d.Rules = nil
+	}
+	d.Expires = time.Time{}
+	if v.Expires != "" {
+		expires, err := time.ParseInLocation(expiresLayout, v.Expires, time.Local)
+		if err != nil {
+			return err
+		}
+		d.Expires = expires
+	}
+	return nil
+}
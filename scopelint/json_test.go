@@ -0,0 +1,45 @@
+package scopelint
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblemJSON(t *testing.T) {
+	l := new(Linter)
+	problems, err := l.Lint("mypkg/mypkg.go", []byte(`package main
+
+func factory() (ret func() *int) {
+	for _, i := range make([]int, 1) {
+		ret = func() *int { return &i } //scopelint:ignore,expires=2999-01-01 // known
+	}
+	return
+}`))
+	require.NoError(t, err)
+	require.Len(t, problems, 2)
+
+	raw, err := json.Marshal(problems[0])
+	require.NoError(t, err)
+
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &fields))
+	assert.Equal(t, "mypkg/mypkg.go", fields["filename"])
+	assert.EqualValues(t, 5, fields["line"])
+	assert.EqualValues(t, 31, fields["column"])
+	assert.EqualValues(t, 32, fields["end"].(map[string]interface{})["column"])
+	assert.Equal(t, "range-scope", fields["category"])
+	assert.Equal(t, true, fields["ignored"])
+	assert.Equal(t, problems[0].Fingerprint(), fields["fingerprint"])
+	assert.Equal(t, "2999-01-01", fields["directive"].(map[string]interface{})["expires"])
+	assert.NotContains(t, fields, "Position")
+
+	var decoded Problem
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	require.NotNil(t, decoded.Directive)
+	assert.Equal(t, *problems[0].Directive, *decoded.Directive)
+	decoded.Directive = problems[0].Directive
+	assert.Equal(t, problems[0], decoded)
+}
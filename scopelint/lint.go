@@ -2,17 +2,44 @@
 package scopelint
 
 import (
+	"bytes"
 	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/printer"
 	"go/token"
 	"go/types"
+	"path"
 	"sort"
+	"strconv"
 	"strings"
 )
 
 // A Linter lints Go source code.
-type Linter struct{}
+type Linter struct {
+	// GoVersion is the language version of the source code like "go1.21".
+	// Since Go 1.22, each iteration of a loop has its own variables, so they are not reported.
+	// Empty means the older semantics.
+	GoVersion string
+
+	// Callbacks are patterns of functions which call their function-literal arguments before returning,
+	// like "sort.Slice" or "*.Run".
+	// A pattern is matched against the callee expression with path.Match.
+	Callbacks []string
+
+	// Configure returns the linter for each package in LintPatterns,
+	// for settings which differ between packages. It may be nil.
+	// It is called concurrently if Jobs is not 1.
+	Configure func(pkg *LoadedPackage) *Linter
+
+	// Jobs is the number of packages linted concurrently in LintPatterns.
+	// Zero or less means runtime.GOMAXPROCS(0).
+	Jobs int
+
+	// Cache stores the problems found in each package in LintPatterns,
+	// so that unchanged packages are not parsed again. It may be nil.
+	Cache Cache
+}
 
 // Lint lints src.
 func (l *Linter) Lint(filename string, src []byte) ([]Problem, error) {
@@ -22,6 +49,25 @@ func (l *Linter) Lint(filename string, src []byte) ([]Problem, error) {
 // LintFiles lints a set of files of a single package.
 // The argument is a map of filename to source.
 func (l *Linter) LintFiles(files map[string][]byte) ([]Problem, error) {
+	pkg, err := l.lintPackage(files)
+	if err != nil || pkg == nil {
+		return nil, err
+	}
+	return pkg.Problems, nil
+}
+
+// Directives lints a set of files of a single package,
+// and returns ignore directives in them with the number of problems they hide.
+// The argument is a map of filename to source.
+func (l *Linter) Directives(files map[string][]byte) ([]*Directive, error) {
+	pkg, err := l.lintPackage(files)
+	if err != nil || pkg == nil {
+		return nil, err
+	}
+	return pkg.Directives, nil
+}
+
+func (l *Linter) lintPackage(files map[string][]byte) (*Package, error) {
 	if len(files) == 0 {
 		return nil, nil
 	}
@@ -29,6 +75,7 @@ func (l *Linter) LintFiles(files map[string][]byte) ([]Problem, error) {
 	pkg := &Package{
 		FileSet: token.NewFileSet(),
 		Files:   make(map[string]*File),
+		linter:  l,
 	}
 
 	var pkgName string
@@ -42,7 +89,7 @@ func (l *Linter) LintFiles(files map[string][]byte) ([]Problem, error) {
 		} else if strings.TrimSuffix(astFile.Name.Name, "_test") != strings.TrimSuffix(pkgName, "_test") {
 			return nil, fmt.Errorf("%s is in package %s, not %s", filename, astFile.Name.Name, pkgName)
 		}
-		pkg.Files[filename] = &File{
+		file := &File{
 			Package:    pkg,
 			ASTFile:    astFile,
 			FileSet:    pkg.FileSet,
@@ -50,8 +97,11 @@ func (l *Linter) LintFiles(files map[string][]byte) ([]Problem, error) {
 			Filename:   filename,
 			CommentMap: ast.NewCommentMap(pkg.FileSet, astFile, astFile.Comments),
 		}
+		file.parseDirectives()
+		pkg.Files[filename] = file
 	}
-	return pkg.lint(), nil
+	pkg.lint()
+	return pkg, nil
 }
 
 // Package represents a package being linted.
@@ -62,7 +112,10 @@ type Package struct {
 	TypesPackage *types.Package
 	TypesInfo    *types.Info
 
-	Problems []Problem
+	Problems   []Problem
+	Directives []*Directive
+
+	linter *Linter
 }
 
 func (p *Package) lint() []Problem {
@@ -71,6 +124,7 @@ func (p *Package) lint() []Problem {
 	}
 
 	sort.Sort(problemsByPosition(p.Problems))
+	sort.Sort(directivesByPosition(p.Directives))
 
 	return p.Problems
 }
@@ -83,6 +137,7 @@ type File struct {
 	Source     []byte
 	Filename   string
 	CommentMap ast.CommentMap
+	Directives map[*ast.Comment]*Directive
 }
 
 func (f *File) lint() {
@@ -90,6 +145,7 @@ func (f *File) lint() {
 		File:          *f,
 		DangerObjects: map[*ast.Object]int{},
 		UnsafeObjects: map[*ast.Object]int{},
+		Loops:         map[*ast.Object]ast.Stmt{},
 		SkipFuncs:     map[*ast.FuncLit]int{},
 	}, f.ASTFile)
 }
@@ -99,8 +155,10 @@ type Node struct {
 	File
 	DangerObjects map[*ast.Object]int
 	UnsafeObjects map[*ast.Object]int
+	Loops         map[*ast.Object]ast.Stmt // loop statements declaring the variables
 	SkipFuncs     map[*ast.FuncLit]int
-	Ignore        bool
+	Ignores       []*Directive
+	Func          string // name of the enclosing function declaration
 }
 
 // Visit method is invoked for each node encountered by Walk.
@@ -111,36 +169,46 @@ func (n *Node) Visit(node ast.Node) ast.Visitor {
 	if node == nil {
 		return &next
 	}
-CGS_LOOP:
 	for _, cg := range n.File.CommentMap[node] {
 		for _, com := range cg.List {
-			if hasOptionComment(com.Text, "ignore") {
-				next.Ignore = true
-				break CGS_LOOP
+			if directive, ok := n.File.Directives[com]; ok {
+				next.Ignores = append(next.Ignores[:len(next.Ignores):len(next.Ignores)], directive)
 			}
 		}
 	}
 	switch typedNode := node.(type) {
+	case *ast.FuncDecl:
+		next.Func = funcName(typedNode)
+
 	case *ast.ForStmt:
+		if n.File.Package.linter.perIterationLoopVar() {
+			break
+		}
 		switch init := typedNode.Init.(type) {
 		case *ast.AssignStmt:
 			for _, lh := range init.Lhs {
 				switch tlh := lh.(type) {
 				case *ast.Ident:
 					n.UnsafeObjects[tlh.Obj] = 0
+					n.Loops[tlh.Obj] = typedNode
 				}
 			}
 		}
 
 	case *ast.RangeStmt:
+		if n.File.Package.linter.perIterationLoopVar() {
+			break
+		}
 		// Memory variables declarated in range statement
 		switch k := typedNode.Key.(type) {
 		case *ast.Ident:
 			n.UnsafeObjects[k.Obj] = 0
+			n.Loops[k.Obj] = typedNode
 		}
 		switch v := typedNode.Value.(type) {
 		case *ast.Ident:
 			n.UnsafeObjects[v.Obj] = 0
+			n.Loops[v.Obj] = typedNode
 		}
 
 	case *ast.UnaryExpr:
@@ -149,7 +217,9 @@ CGS_LOOP:
 			case *ast.Ident:
 				if _, unsafe := n.UnsafeObjects[ident.Obj]; unsafe {
 					ref := ""
-					n.errorf(ident, 1, n.Ignore, link(ref), category("range-scope"), "Using a reference for the variable on range scope %q", ident.Name)
+					p := n.errorf(ident, 1, n.Ignores, link(ref), category("range-scope"), "Using a reference for the variable on range scope %q", ident.Name)
+					p.Function, p.Variable = n.Func, ident.Name
+					n.suggestPin(p, ident)
 				}
 			}
 		}
@@ -158,7 +228,9 @@ CGS_LOOP:
 		if _, obj := n.DangerObjects[typedNode.Obj]; obj {
 			// It is the naked variable in scope of range statement.
 			ref := ""
-			n.errorf(node, 1, n.Ignore, link(ref), category("range-scope"), "Using the variable on range scope %q in function literal", typedNode.Name)
+			p := n.errorf(node, 1, n.Ignores, link(ref), category("range-scope"), "Using the variable on range scope %q in function literal", typedNode.Name)
+			p.Function, p.Variable = n.Func, typedNode.Name
+			n.suggestPin(p, typedNode)
 			break
 		}
 
@@ -168,6 +240,13 @@ CGS_LOOP:
 		case *ast.FuncLit:
 			n.SkipFuncs[funcLit] = 0
 		}
+		if n.File.Package.linter.isCallback(typedNode.Fun) {
+			for _, arg := range typedNode.Args {
+				if funcLit, ok := arg.(*ast.FuncLit); ok {
+					n.SkipFuncs[funcLit] = 0
+				}
+			}
+		}
 
 	case *ast.FuncLit:
 		if _, skip := n.SkipFuncs[typedNode]; !skip {
@@ -197,25 +276,148 @@ CGS_LOOP:
 	return &next
 }
 
+// suggestPin adds the loop declaring the variable to the related locations of the problem,
+// and suggests a fix to pin the variable in the loop body.
+func (n *Node) suggestPin(p *Problem, ident *ast.Ident) {
+	loop, ok := n.Loops[ident.Obj]
+	if !ok {
+		return
+	}
+	var body *ast.BlockStmt
+	switch typed := loop.(type) {
+	case *ast.ForStmt:
+		body = typed.Body
+	case *ast.RangeStmt:
+		body = typed.Body
+	}
+
+	header := n.File.FileSet.Position(loop.Pos())
+	p.Related = append(p.Related, RelatedLocation{
+		Position: header,
+		Message:  fmt.Sprintf("The variable %q is declared in the loop here", ident.Name),
+	})
+
+	// Insert a pin just after the left brace of the loop body, indented one more than the loop.
+	// If the body is on the line of the brace, the pin is a statement on the line instead,
+	// so that it does not comment out the rest of the body.
+	lbrace := n.File.FileSet.PositionFor(body.Lbrace+1, false)
+	newText := fmt.Sprintf(" %s := %s;", ident.Name, ident.Name)
+	if !n.bodyOnBraceLine(body) {
+		indent := leadingIndent(srcLine(n.File.Source, n.File.FileSet.PositionFor(loop.Pos(), false)))
+		newText = fmt.Sprintf("\n%s\t%s := %s // pin", indent, ident.Name, ident.Name)
+	}
+	p.Fixes = append(p.Fixes, Fix{
+		Message: fmt.Sprintf("Pin the variable %q in the loop body", ident.Name),
+		Edits: []Edit{{
+			Position: lbrace,
+			End:      lbrace,
+			NewText:  newText,
+		}},
+	})
+}
+
+// bodyOnBraceLine reports whether the body has a statement or the right brace on the line of its left brace.
+func (n *Node) bodyOnBraceLine(body *ast.BlockStmt) bool {
+	line := n.File.FileSet.PositionFor(body.Lbrace, false).Line
+	next := body.Rbrace
+	if len(body.List) > 0 {
+		next = body.List[0].Pos()
+	}
+	return n.File.FileSet.PositionFor(next, false).Line == line
+}
+
+func leadingIndent(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// perIterationLoopVar reports whether each iteration of a loop has its own variables.
+func (l *Linter) perIterationLoopVar() bool {
+	major, minor, ok := parseGoVersion(l.GoVersion)
+	return ok && (major > 1 || major == 1 && minor >= 22)
+}
+
+// parseGoVersion parses a language version like "go1.22", "1.22" or "go1.22.1".
+func parseGoVersion(version string) (major, minor int, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "go"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	digits := strings.IndexFunc(parts[1], func(r rune) bool { return r < '0' || '9' < r })
+	if digits >= 0 {
+		parts[1] = parts[1][:digits] // trim pre-release suffix like "rc1"
+	}
+	minor, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// isCallback reports whether the callee matches any of the Callbacks.
+func (l *Linter) isCallback(callee ast.Expr) bool {
+	if len(l.Callbacks) == 0 {
+		return false
+	}
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, token.NewFileSet(), callee); err != nil {
+		return false
+	}
+	for _, pattern := range l.Callbacks {
+		if matched, _ := path.Match(pattern, buf.String()); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// funcName returns the name of the function declaration like `Func` or `(Type).Method`.
+func funcName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+	recv := decl.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	switch typ := recv.(type) {
+	case *ast.Ident:
+		return "(" + typ.Name + ")." + decl.Name.Name
+	case *ast.IndexExpr:
+		if ident, ok := typ.X.(*ast.Ident); ok {
+			return "(" + ident.Name + ")." + decl.Name.Name
+		}
+	}
+	return decl.Name.Name
+}
+
 type link string
 type category string
 
 // The variadic arguments may start with link and category types,
 // and must end with a format string and any arguments.
+// If any of the ignores mutes the category, the problem is marked as ignored.
 // It returns the new Problem.
-func (f *File) errorf(n ast.Node, confidence float64, ignore bool, args ...interface{}) *Problem {
+func (f *File) errorf(n ast.Node, confidence float64, ignores []*Directive, args ...interface{}) *Problem {
 	pos := f.FileSet.Position(n.Pos())
 	if pos.Filename == "" {
 		pos.Filename = f.Filename
 	}
-	return f.Package.errorfAt(pos, confidence, ignore, args...)
+	problem := f.Package.errorfAt(pos, confidence, ignores, args...)
+	problem.End = f.FileSet.Position(n.End())
+	if problem.End.Filename == "" {
+		problem.End.Filename = f.Filename
+	}
+	return problem
 }
 
-func (p *Package) errorfAt(pos token.Position, confidence float64, ignore bool, args ...interface{}) *Problem {
+func (p *Package) errorfAt(pos token.Position, confidence float64, ignores []*Directive, args ...interface{}) *Problem {
 	problem := Problem{
 		Position:   pos,
 		Confidence: confidence,
-		Ignored:    ignore,
 	}
 	if pos.Filename != "" {
 		// The file might not exist in our mapping if a //line directive was encountered.
@@ -239,6 +441,12 @@ argLoop:
 
 	problem.Text = fmt.Sprintf(args[0].(string), args[1:]...)
 
+	if directive := suppressor(ignores, problem.Category); directive != nil {
+		problem.Ignored = true
+		problem.Directive = directive
+		directive.Hidden++
+	}
+
 	p.Problems = append(p.Problems, problem)
 	return &p.Problems[len(p.Problems)-1]
 }
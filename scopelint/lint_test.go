@@ -231,3 +231,115 @@ func TestSomething(t *testing.T) {
 		})
 	})
 }
+
+func TestLinterOptions(t *testing.T) {
+	src := []byte(`package main
+
+import "testing"
+
+func TestSomething(t *testing.T) {
+	for _, tc := range []struct {
+		expected string
+	}{} {
+		t.Run("sub", func(t *testing.T) {
+			if "result" != tc.expected {
+				t.Fatal("failed")
+			}
+		})
+	}
+}`)
+
+	t.Run("go version before 1.22", func(t *testing.T) {
+		l := &Linter{GoVersion: "go1.21"}
+		problems, err := l.Lint("mypkg/mypkg.go", src)
+		require.NoError(t, err)
+		assert.Len(t, problems, 1)
+	})
+
+	t.Run("go version since 1.22", func(t *testing.T) {
+		for _, version := range []string{"go1.22", "1.22.1", "go1.23rc1", "2.0"} {
+			l := &Linter{GoVersion: version}
+			problems, err := l.Lint("mypkg/mypkg.go", src)
+			require.NoError(t, err)
+			assert.Empty(t, problems, version)
+		}
+	})
+
+	t.Run("callbacks", func(t *testing.T) {
+		l := &Linter{Callbacks: []string{"*.Run"}}
+		problems, err := l.Lint("mypkg/mypkg.go", src)
+		require.NoError(t, err)
+		assert.Empty(t, problems)
+	})
+
+	t.Run("unmatched callbacks", func(t *testing.T) {
+		l := &Linter{Callbacks: []string{"sort.Slice"}}
+		problems, err := l.Lint("mypkg/mypkg.go", src)
+		require.NoError(t, err)
+		assert.Len(t, problems, 1)
+	})
+}
+
+func TestSuggestPin(t *testing.T) {
+	src := `package main
+
+func factory() (ret func() *int) {
+	for _, i := range make([]int, 1) {
+		ret = func() *int { return &i }
+	}
+	return
+}`
+	l := new(Linter)
+	problems, err := l.Lint("mypkg/mypkg.go", []byte(src))
+	require.NoError(t, err)
+	require.Len(t, problems, 2)
+
+	p := problems[0]
+	if assert.Len(t, p.Related, 1) {
+		assert.Equal(t, 4, p.Related[0].Position.Line)
+		assert.Equal(t, 2, p.Related[0].Position.Column)
+	}
+	require.Len(t, p.Fixes, 1)
+	require.Len(t, p.Fixes[0].Edits, 1)
+	edit := p.Fixes[0].Edits[0]
+	fixed := src[:edit.Position.Offset] + edit.NewText + src[edit.End.Offset:]
+	assert.Equal(t, `package main
+
+func factory() (ret func() *int) {
+	for _, i := range make([]int, 1) {
+		i := i // pin
+		ret = func() *int { return &i }
+	}
+	return
+}`, fixed)
+
+	problems, err = l.Lint("mypkg/mypkg.go", []byte(fixed))
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestSuggestPinOneLineBody(t *testing.T) {
+	src := `package main
+
+func pointers(xs []int) (ps []*int) {
+	for _, v := range xs { ps = append(ps, &v) }
+	return
+}`
+	l := new(Linter)
+	problems, err := l.Lint("mypkg/mypkg.go", []byte(src))
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	require.Len(t, problems[0].Fixes, 1)
+	edit := problems[0].Fixes[0].Edits[0]
+	fixed := src[:edit.Position.Offset] + edit.NewText + src[edit.End.Offset:]
+	assert.Equal(t, `package main
+
+func pointers(xs []int) (ps []*int) {
+	for _, v := range xs { v := v; ps = append(ps, &v) }
+	return
+}`, fixed)
+
+	problems, err = l.Lint("mypkg/mypkg.go", []byte(fixed))
+	require.NoError(t, err)
+	assert.Empty(t, problems, "the fixed source is valid and pinned")
+}
@@ -0,0 +1,283 @@
+package scopelint
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// A Loader loads the packages matching patterns to lint.
+//
+// The meaning of the patterns depends on the loader, like import paths, directories or file names.
+// Packages which cannot be loaded are returned with their errors,
+// and an error is returned only if the patterns cannot be resolved at all.
+type Loader interface {
+	Load(ctx context.Context, patterns ...string) ([]*LoadedPackage, error)
+}
+
+// A LazyLoader is a Loader which resolves the packages matching patterns before reading their files,
+// so that LintPatternsFunc reads the files of each package in its workers.
+type LazyLoader interface {
+	Loader
+
+	// Resolve returns a function for each package Load returns, in the same order,
+	// which reads the files of the package and returns it. The functions are called concurrently.
+	Resolve(ctx context.Context, patterns ...string) ([]func() *LoadedPackage, error)
+}
+
+// Resolve resolves the packages matching the patterns with the loader like LazyLoader.Resolve.
+// Loaders which are not LazyLoader load all packages at once.
+func Resolve(ctx context.Context, loader Loader, patterns ...string) ([]func() *LoadedPackage, error) {
+	if l, ok := loader.(LazyLoader); ok {
+		return l.Resolve(ctx, patterns...)
+	}
+	pkgs, err := loader.Load(ctx, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	loads := make([]func() *LoadedPackage, len(pkgs))
+	for i, pkg := range pkgs {
+		pkg := pkg
+		loads[i] = func() *LoadedPackage { return pkg }
+	}
+	return loads, nil
+}
+
+// A LoadedPackage is a package loaded by a Loader.
+type LoadedPackage struct {
+	Name  string            // import path of the package, or its directory if it has none
+	Dir   string            // directory of the package
+	Files map[string][]byte // filename => source of the files to lint
+	Build BuildInfo         // how the package is built
+	Err   error             // error in loading the package; Files may be incomplete if set
+}
+
+// Filenames returns the names of the files of the package in order.
+func (p *LoadedPackage) Filenames() []string {
+	filenames := make([]string, 0, len(p.Files))
+	for filename := range p.Files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	return filenames
+}
+
+// BuildInfo is the information of how a package is built.
+type BuildInfo struct {
+	Module    *Module  // module containing the package, or nil if it is unknown
+	GoVersion string   // language version of the package, or empty if it is unknown
+	GOOS      string   // target operating system
+	GOARCH    string   // target architecture
+	BuildTags []string // custom build tags
+
+	// Contexts are the names of the build contexts like "linux/amd64,integration" each file is built in,
+	// when the files are loaded for several build contexts.
+	Contexts map[string][]string
+}
+
+// CommandLineArguments is the name of the package consisting of files given explicitly, like the go command.
+const CommandLineArguments = "command-line-arguments"
+
+// FilesLoader loads a package from an explicit list of files.
+// The patterns are the names of the files, which are in a single package.
+type FilesLoader struct {
+	// ReadFile reads a file. If it is nil, files are read from disk.
+	ReadFile func(filename string) ([]byte, error)
+}
+
+// Load loads the files as a package named CommandLineArguments.
+func (l *FilesLoader) Load(ctx context.Context, patterns ...string) ([]*LoadedPackage, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	readFile := l.ReadFile
+	if readFile == nil {
+		readFile = ioutil.ReadFile
+	}
+	dir := filepath.Dir(patterns[0])
+	pkg := &LoadedPackage{
+		Name:  CommandLineArguments,
+		Dir:   dir,
+		Files: map[string][]byte{},
+		Build: moduleBuildInfo(dir),
+	}
+	for _, filename := range patterns {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		src, err := readFile(filename)
+		if err != nil {
+			pkg.Err = err
+			continue
+		}
+		pkg.Files[filename] = src
+	}
+	return []*LoadedPackage{pkg}, nil
+}
+
+// moduleBuildInfo returns the build information of the module containing the directory if any.
+func moduleBuildInfo(dir string) BuildInfo {
+	mod, err := FindModule(dir)
+	if err != nil || mod == nil {
+		return BuildInfo{}
+	}
+	return BuildInfo{Module: mod, GoVersion: mod.GoVersion}
+}
+
+// Result is the result of linting a package loaded by a Loader.
+type Result struct {
+	Package  *LoadedPackage
+	Problems []Problem
+	Err      error // error in loading or linting the package
+}
+
+// LintPatterns loads the packages matching the patterns with the loader, and lints each of them.
+// The results are in the order of the packages returned by the loader (see LintPatternsFunc).
+func (l *Linter) LintPatterns(ctx context.Context, loader Loader, patterns ...string) ([]Result, error) {
+	var results []Result
+	err := l.LintPatternsFunc(ctx, loader, func(result Result) error {
+		results = append(results, result)
+		return nil
+	}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// LintPatternsFunc loads the packages matching the patterns with the loader, lints each of them,
+// and calls report with the result of each package as soon as it and all packages before it are linted.
+// Packages are read (if the loader is a LazyLoader), parsed and linted by Jobs workers concurrently,
+// and the results are reported in the order of the packages returned by the loader.
+// If report returns an error, linting stops and the error is returned.
+//
+// Each package is linted by the linter returned by Configure if it is set.
+// Otherwise, it is linted by l, with the language version of the package if GoVersion is empty.
+func (l *Linter) LintPatternsFunc(ctx context.Context, loader Loader, report func(Result) error, patterns ...string) error {
+	loads, err := Resolve(ctx, loader, patterns...)
+	if err != nil {
+		return err
+	}
+
+	jobs := l.Jobs
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	if jobs > len(loads) {
+		jobs = len(loads)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// Workers go ahead of the reported package by the window at most,
+	// so that results waiting for the ones before them do not pile up.
+	window := make(chan struct{}, 2*jobs)
+	done := make([]chan Result, len(loads))
+	for i := range done {
+		done[i] = make(chan Result, 1)
+	}
+	indices := make(chan int)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(indices)
+		for i := range loads {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case indices <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				done[i] <- l.lintLoaded(loads[i]())
+			}
+		}()
+	}
+
+	for i := range loads {
+		select {
+		case result := <-done[i]:
+			<-window
+			if err := report(result); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return ctx.Err()
+}
+
+func (l *Linter) lintLoaded(pkg *LoadedPackage) Result {
+	result := Result{Package: pkg, Err: pkg.Err}
+	if pkg.Err != nil {
+		return result
+	}
+	linter := l.configure(pkg)
+	problems, ok := l.cached(pkg, linter)
+	if !ok {
+		var err error
+		problems, err = linter.LintFiles(pkg.Files)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if l.Cache != nil {
+			l.Cache.Put(pkg, linter, problems)
+		}
+	}
+	for i, p := range problems {
+		problems[i].BuildContexts = pkg.Build.Contexts[p.Position.Filename]
+	}
+	result.Problems = problems
+	return result
+}
+
+// cached returns the problems in the package stored in the cache.
+func (l *Linter) cached(pkg *LoadedPackage, linter *Linter) ([]Problem, bool) {
+	if l.Cache == nil {
+		return nil, false
+	}
+	return l.Cache.Get(pkg, linter)
+}
+
+// A Cache stores the problems found in packages by LintPatterns.
+// It must be safe for concurrent use.
+//
+// The problems depend on the files of the package and the settings of the linter,
+// and problems ignored by directives which expire are valid only until the directives expire.
+type Cache interface {
+	// Get returns the problems in the package found by the linter before, or false if they are not stored.
+	Get(pkg *LoadedPackage, linter *Linter) ([]Problem, bool)
+	// Put stores the problems in the package found by the linter.
+	Put(pkg *LoadedPackage, linter *Linter, problems []Problem)
+}
+
+// configure returns the linter for the package.
+func (l *Linter) configure(pkg *LoadedPackage) *Linter {
+	if l.Configure != nil {
+		return l.Configure(pkg)
+	}
+	linter := *l
+	if linter.GoVersion == "" {
+		linter.GoVersion = pkg.Build.GoVersion
+	}
+	return &linter
+}
@@ -0,0 +1,319 @@
+package scopelint
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const loaderSource = `package p
+
+func f() (fs []func()) {
+	for _, v := range []int{1} {
+		fs = append(fs, func() { print(v) })
+	}
+	return
+}
+`
+
+// writeModule writes a module example.com/m with the packages p and p/q into a temporary directory.
+func writeModule(t *testing.T, goVersion string) string {
+	root, err := ioutil.TempDir("", "scopelint-loader")
+	require.NoError(t, err)
+	root, err = filepath.EvalSymlinks(root)
+	require.NoError(t, err)
+	for name, content := range map[string]string{
+		"go.mod":        "module example.com/m\n\ngo " + goVersion + "\n",
+		"p/p.go":        loaderSource,
+		"p/p_test.go":   "package p\n",
+		"p/q/q.go":      "package q\n",
+		"p/r/README.md": "",
+	} {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		require.NoError(t, ioutil.WriteFile(name, []byte(content), 0644))
+	}
+	return root
+}
+
+func TestBuildLoader(t *testing.T) {
+	root := writeModule(t, "1.21")
+	defer os.RemoveAll(root)
+
+	pkgs, err := (&BuildLoader{}).Load(context.Background(), filepath.Join(root, "p")+"/...")
+	require.NoError(t, err)
+	require.Len(t, pkgs, 2)
+	assert.Equal(t, "example.com/m/p", pkgs[0].Name)
+	assert.Equal(t, []string{filepath.Join(root, "p", "p.go")}, pkgs[0].Filenames())
+	assert.Equal(t, "1.21", pkgs[0].Build.GoVersion)
+	assert.Equal(t, filepath.Join(root, "go.mod"), pkgs[0].Build.Module.GoMod)
+	assert.Equal(t, "example.com/m/p/q", pkgs[1].Name)
+
+	pkgs, err = (&BuildLoader{Tests: true}).Load(context.Background(), filepath.Join(root, "p"))
+	require.NoError(t, err)
+	require.Len(t, pkgs, 1)
+	assert.Len(t, pkgs[0].Files, 2)
+}
+
+func TestBuildLoaderResolve(t *testing.T) {
+	root := writeModule(t, "1.21")
+	defer os.RemoveAll(root)
+
+	loads, err := (&BuildLoader{}).Resolve(context.Background(), filepath.Join(root, "p")+"/...", filepath.Join(root, "p"))
+	require.NoError(t, err)
+	require.Len(t, loads, 2, "packages in the same directories are resolved once")
+
+	// Files are read when the packages are loaded.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "p", "q", "q.go"), []byte("package q\n\nvar v int\n"), 0644))
+	pkg := loads[1]()
+	assert.Equal(t, "example.com/m/p/q", pkg.Name)
+	assert.Equal(t, "package q\n\nvar v int\n", string(pkg.Files[filepath.Join(root, "p", "q", "q.go")]))
+}
+
+func TestImportPaths(t *testing.T) {
+	root := writeModule(t, "1.21")
+	defer os.RemoveAll(root)
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(root))
+	defer os.Chdir(wd)
+
+	loader := &ModuleLoader{Workspace: WorkspaceOff}
+	names, err := loader.ImportPaths("./...", "example.com/m/p/q", "./p/q")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"./p", "./p/q", "example.com/m/p/q"}, names, "duplicates are removed")
+
+	pkgs, err := loader.Load(context.Background(), names...)
+	require.NoError(t, err)
+	require.Len(t, pkgs, 2, "the packages are loaded once")
+	assert.Equal(t, "example.com/m/p", pkgs[0].Name)
+	assert.Equal(t, "example.com/m/p/q", pkgs[1].Name)
+}
+
+func TestModuleLoader(t *testing.T) {
+	root := writeModule(t, "1.21")
+	defer os.RemoveAll(root)
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(root))
+	defer os.Chdir(wd)
+
+	loader := &ModuleLoader{Workspace: WorkspaceOff}
+	pkgs, err := loader.Load(context.Background(), "all")
+	require.NoError(t, err)
+	var names []string
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Name)
+	}
+	assert.Equal(t, []string{"example.com/m/p", "example.com/m/p/q"}, names)
+	assert.Equal(t, filepath.FromSlash("p/p.go"), pkgs[0].Filenames()[0], "files in the working directory are relative")
+
+	pkgs, err = loader.Load(context.Background(), "example.com/m/none")
+	require.NoError(t, err)
+	require.Len(t, pkgs, 1)
+	assert.Error(t, pkgs[0].Err)
+}
+
+func TestFilesLoader(t *testing.T) {
+	loader := &FilesLoader{ReadFile: func(filename string) ([]byte, error) {
+		return []byte(loaderSource), nil
+	}}
+	pkgs, err := loader.Load(context.Background(), "x/a.go", "x/b.go")
+	require.NoError(t, err)
+	require.Len(t, pkgs, 1)
+	assert.Equal(t, CommandLineArguments, pkgs[0].Name)
+	assert.Equal(t, "x", pkgs[0].Dir)
+	assert.Equal(t, []string{"x/a.go", "x/b.go"}, pkgs[0].Filenames())
+}
+
+func TestGoListLoader(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command is not found")
+	}
+	root := writeModule(t, "1.21")
+	defer os.RemoveAll(root)
+
+	loader := &GoListLoader{Dir: root, Env: append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")}
+	pkgs, err := loader.Load(context.Background(), "./...")
+	require.NoError(t, err)
+	require.Len(t, pkgs, 2)
+	assert.Equal(t, "example.com/m/p", pkgs[0].Name)
+	assert.Equal(t, []string{filepath.Join(root, "p", "p.go")}, pkgs[0].Filenames())
+	assert.Equal(t, "1.21", pkgs[0].Build.GoVersion)
+	assert.NotEmpty(t, pkgs[0].Build.GOOS)
+}
+
+func TestLintPatterns(t *testing.T) {
+	root := writeModule(t, "1.21")
+	defer os.RemoveAll(root)
+	loader := &BuildLoader{}
+
+	results, err := new(Linter).LintPatterns(context.Background(), loader, filepath.Join(root, "p"))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.NoError(t, results[0].Err)
+	assert.Len(t, results[0].Problems, 1)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/m\n\ngo 1.22\n"), 0644))
+	delete(modules, root)
+	results, err = new(Linter).LintPatterns(context.Background(), loader, filepath.Join(root, "p"))
+	require.NoError(t, err)
+	assert.Empty(t, results[0].Problems, "the language version of the module is used")
+
+	results, err = (&Linter{Configure: func(*LoadedPackage) *Linter {
+		return &Linter{GoVersion: "go1.21"}
+	}}).LintPatterns(context.Background(), loader, filepath.Join(root, "p"))
+	require.NoError(t, err)
+	assert.Len(t, results[0].Problems, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = new(Linter).LintPatterns(ctx, loader, filepath.Join(root, "p"))
+	assert.Equal(t, context.Canceled, err)
+}
+
+// staticLoader is a loader of packages in memory.
+type staticLoader []*LoadedPackage
+
+func (l staticLoader) Load(context.Context, ...string) ([]*LoadedPackage, error) {
+	return l, nil
+}
+
+func TestLintPatternsJobs(t *testing.T) {
+	var loader staticLoader
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("p%02d", i)
+		loader = append(loader, &LoadedPackage{
+			Name:  name,
+			Files: map[string][]byte{name + "/p.go": []byte(loaderSource)},
+		})
+	}
+	results, err := (&Linter{Jobs: 4}).LintPatterns(context.Background(), loader)
+	require.NoError(t, err)
+	require.Len(t, results, len(loader))
+	for i, result := range results {
+		assert.Equal(t, loader[i].Name, result.Package.Name, "results are in the order of the packages")
+		assert.Len(t, result.Problems, 1)
+	}
+}
+
+func TestLintPatternsFunc(t *testing.T) {
+	var loader staticLoader
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("p%02d", i)
+		loader = append(loader, &LoadedPackage{
+			Name:  name,
+			Files: map[string][]byte{name + "/p.go": []byte(loaderSource)},
+		})
+	}
+
+	first := make(chan struct{})
+	streamed := false
+	l := &Linter{Jobs: 2, Configure: func(pkg *LoadedPackage) *Linter {
+		if pkg.Name == "p01" {
+			// Linted while the first result is reported.
+			select {
+			case <-first:
+				streamed = true
+			case <-time.After(5 * time.Second):
+			}
+		}
+		return &Linter{}
+	}}
+	var names []string
+	require.NoError(t, l.LintPatternsFunc(context.Background(), loader, func(result Result) error {
+		if result.Package.Name == "p00" {
+			close(first)
+		}
+		names = append(names, result.Package.Name)
+		return nil
+	}))
+	assert.True(t, streamed, "results are reported before all packages are linted")
+	require.Len(t, names, len(loader))
+	for i, name := range names {
+		assert.Equal(t, loader[i].Name, name, "results are reported in the order of the packages")
+	}
+
+	stop := errors.New("stop")
+	reported := 0
+	err := new(Linter).LintPatternsFunc(context.Background(), loader, func(Result) error {
+		reported++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, reported, "linting stops at the error of the report")
+}
+
+func BenchmarkLintPatterns(b *testing.B) {
+	var src bytes.Buffer
+	src.WriteString("package p\n")
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&src, "\nfunc f%d() (fs []func()) {\n\tfor _, v := range []int{1} {\n\t\tfs = append(fs, func() { print(v) })\n\t}\n\treturn\n}\n", i)
+	}
+	var loader staticLoader
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("p%03d", i)
+		loader = append(loader, &LoadedPackage{
+			Name:  name,
+			Files: map[string][]byte{name + "/a.go": src.Bytes()},
+		})
+	}
+	// The speedup is bounded by GOMAXPROCS.
+	for _, jobs := range []int{1, 2, 4, 8} {
+		jobs := jobs
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			l := &Linter{Jobs: jobs}
+			for i := 0; i < b.N; i++ {
+				if _, err := l.LintPatterns(context.Background(), loader); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// memoryCache is a cache keyed by package names.
+type memoryCache struct {
+	sync.Mutex
+	problems map[string][]Problem
+}
+
+func (c *memoryCache) Get(pkg *LoadedPackage, _ *Linter) ([]Problem, bool) {
+	c.Lock()
+	defer c.Unlock()
+	problems, ok := c.problems[pkg.Name]
+	return problems, ok
+}
+
+func (c *memoryCache) Put(pkg *LoadedPackage, _ *Linter, problems []Problem) {
+	c.Lock()
+	defer c.Unlock()
+	c.problems[pkg.Name] = problems
+}
+
+func TestLintPatternsCache(t *testing.T) {
+	loader := staticLoader{{Name: "p", Files: map[string][]byte{"p/p.go": []byte(loaderSource)}}}
+	cache := &memoryCache{problems: map[string][]Problem{}}
+	l := &Linter{Cache: cache}
+
+	results, err := l.LintPatterns(context.Background(), loader)
+	require.NoError(t, err)
+	assert.Len(t, results[0].Problems, 1)
+	assert.Len(t, cache.problems["p"], 1)
+
+	cache.problems["p"] = nil
+	results, err = l.LintPatterns(context.Background(), loader)
+	require.NoError(t, err)
+	assert.Empty(t, results[0].Problems, "cached problems are returned without linting")
+}
@@ -0,0 +1,202 @@
+package scopelint
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// modFilename is the name of the file defining a Go module.
+const modFilename = "go.mod"
+
+// A Module is a Go module defined by a go.mod file.
+// It is read by scopelint itself, so that packages are resolved offline without the go command.
+type Module struct {
+	Path      string            // module path
+	Dir       string            // absolute directory containing go.mod
+	GoMod     string            // absolute path of the go.mod file
+	GoVersion string            // language version in the go directive
+	Replace   map[string]string // module paths replaced with local directories, to the absolute directories
+}
+
+// modules caches modules by the directories containing go.mod.
+var (
+	modules   = map[string]*Module{}
+	modulesMu sync.Mutex
+)
+
+func cachedModule(dir string) (*Module, bool) {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	mod, ok := modules[dir]
+	return mod, ok
+}
+
+// FindModule returns the module containing the directory, or nil if the directory is not in a module.
+func FindModule(dir string) (*Module, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if mod, ok := cachedModule(dir); ok {
+			return mod, nil
+		}
+		if isFileExists(filepath.Join(dir, modFilename)) {
+			return readModule(dir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// readModule reads the go.mod file in the directory.
+func readModule(dir string) (*Module, error) {
+	if mod, ok := cachedModule(dir); ok {
+		return mod, nil
+	}
+	filename := filepath.Join(dir, modFilename)
+	mod := &Module{Dir: dir, GoMod: filename, Replace: map[string]string{}}
+	if err := readModFile(filename, mod.directive); err != nil {
+		return nil, err
+	}
+	if mod.Path == "" {
+		return nil, fmt.Errorf("%s: no module directive", filename)
+	}
+	modulesMu.Lock()
+	modules[dir] = mod
+	modulesMu.Unlock()
+	return mod, nil
+}
+
+// readModFile reads directives in a go.mod or go.work file.
+// Directives in blocks like `replace ( ... )` are given one by one with the verb of the block.
+func readModFile(filename string, directive func(verb string, args []string) error) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var block string // the verb of the current block
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case block != "":
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			err = directive(block, fields)
+		case len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+			continue
+		default:
+			err = directive(fields[0], fields[1:])
+		}
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", filename, n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// directive reads a directive of go.mod. Directives which scopelint does not need are skipped.
+func (m *Module) directive(verb string, args []string) error {
+	if err := unquoteArgs(args); err != nil {
+		return err
+	}
+	switch verb {
+	case "module":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: module module/path")
+		}
+		m.Path = args[0]
+	case "go":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: go 1.23")
+		}
+		m.GoVersion = args[0]
+	case "replace":
+		return readReplace(m.Dir, args, m.Replace)
+	}
+	return nil
+}
+
+func unquoteArgs(args []string) error {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, `"`) || strings.HasPrefix(arg, "`") {
+			unquoted, err := strconv.Unquote(arg)
+			if err != nil {
+				return err
+			}
+			args[i] = unquoted
+		}
+	}
+	return nil
+}
+
+// readReplace reads the arguments of a replace directive in a file in the directory.
+// Replacements with local directories are stored in replace.
+func readReplace(dir string, args []string, replace map[string]string) error {
+	arrow := -1
+	for i, arg := range args {
+		if arg == "=>" {
+			arrow = i
+		}
+	}
+	if arrow < 1 || arrow == len(args)-1 {
+		return fmt.Errorf("usage: replace module/path [v1.2.3] => other/module [v1.4.5] | ../local/directory")
+	}
+	if target := args[arrow+1]; isLocalPath(target) {
+		replace[args[0]] = absPath(dir, target)
+	}
+	return nil
+}
+
+// isLocalPath reports whether the target of a replace directive is a local directory.
+func isLocalPath(target string) bool {
+	return filepath.IsAbs(target) || path.IsAbs(target) ||
+		target == "." || target == ".." ||
+		strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../") ||
+		strings.HasPrefix(target, `.\`) || strings.HasPrefix(target, `..\`)
+}
+
+// absPath returns the absolute path of the slash-separated path relative to the directory.
+func absPath(dir, p string) string {
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p)
+	}
+	return filepath.Join(dir, filepath.FromSlash(p))
+}
+
+// importPath returns the import path of the package in the directory of the module.
+func (m *Module) importPath(dir string) (string, bool) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(m.Dir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if rel == "." {
+		return m.Path, true
+	}
+	return path.Join(m.Path, filepath.ToSlash(rel)), true
+}
@@ -0,0 +1,127 @@
+package scopelint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModule(t *testing.T) {
+	root, err := ioutil.TempDir("", "scopelint-module")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	root, err = filepath.EvalSymlinks(root)
+	require.NoError(t, err)
+
+	write := func(name, content string) {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		require.NoError(t, ioutil.WriteFile(name, []byte(content), 0644))
+	}
+	write("m/go.mod", `module example.com/m // the main module
+
+go 1.12
+
+require example.com/lib v0.0.0
+
+replace (
+	example.com/lib v0.0.0 => ../lib
+	example.com/remote => example.com/fork v1.0.0
+)
+`)
+	write("m/m.go", "package m\n")
+	write("m/a/a.go", "package a\n")
+	write("m/a/testdata/t.go", "package t\n")
+	write("m/empty/README", "")
+	write("m/nested/go.mod", "module example.com/m/nested\n")
+	write("m/nested/n/n.go", "package n\n")
+	write("lib/go.mod", `module "example.com/lib"`)
+	write("lib/x/x.go", "package x\n")
+
+	isPackage := (&BuildLoader{}).resolver(nil).isPackage
+	mod, err := FindModule(filepath.Join(root, "m", "a"))
+	require.NoError(t, err)
+	require.NotNil(t, mod)
+	assert.Equal(t, "example.com/m", mod.Path)
+	assert.Equal(t, filepath.Join(root, "m"), mod.Dir)
+	assert.Equal(t, map[string]string{"example.com/lib": filepath.Join(root, "lib")}, mod.Replace)
+
+	assert.Equal(t, "1.12", mod.GoVersion)
+
+	w := &workspace{Modules: []*Module{mod}}
+	assert.Equal(t, []string{"example.com/m", "example.com/m/a"}, w.matchPackages(allPackage, isPackage))
+	assert.Equal(t, []string{"example.com/m/a"}, w.matchPackages("example.com/m/a/...", isPackage))
+	assert.Equal(t, []string{"example.com/lib/x"}, w.matchPackages("example.com/lib/...", isPackage))
+
+	dir, ok := w.dir("example.com/lib/x")
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(root, "lib", "x"), dir)
+	_, ok = w.dir("example.com/m/nested/n")
+	assert.False(t, ok, "packages in nested modules are out of the module")
+	_, ok = w.dir("example.com/remote")
+	assert.False(t, ok, "remote replacements are not resolved")
+
+	importPath, ok := mod.importPath(filepath.Join(root, "m", "a"))
+	assert.True(t, ok)
+	assert.Equal(t, "example.com/m/a", importPath)
+
+	none, err := FindModule(os.TempDir())
+	require.NoError(t, err)
+	assert.Nil(t, none)
+}
+
+func TestWorkspace(t *testing.T) {
+	root, err := ioutil.TempDir("", "scopelint-workspace")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	root, err = filepath.EvalSymlinks(root)
+	require.NoError(t, err)
+
+	write := func(name, content string) {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		require.NoError(t, ioutil.WriteFile(name, []byte(content), 0644))
+	}
+	write(workFilename, `go 1.22
+
+use (
+	./a
+	./b // comment
+)
+
+replace example.com/lib => ./lib
+`)
+	write("a/go.mod", "module example.com/a\n\ngo 1.21\n")
+	write("a/a.go", "package a\n")
+	write("b/go.mod", "module example.com/b\n\ngo 1.22\n")
+	write("b/x/x.go", "package x\n")
+	write("c/go.mod", "module example.com/c\n")
+	write("c/c.go", "package c\n")
+	write("lib/go.mod", "module example.com/lib\n")
+	write("lib/lib.go", "package lib\n")
+
+	isPackage := (&BuildLoader{}).resolver(nil).isPackage
+	w, err := readWorkspace(filepath.Join(root, workFilename))
+	require.NoError(t, err)
+	require.Len(t, w.Modules, 2)
+	assert.Equal(t, "1.21", w.Modules[0].GoVersion)
+	assert.Equal(t, "1.22", w.Modules[1].GoVersion)
+	assert.True(t, w.used(filepath.Join(root, "b")))
+	assert.False(t, w.used(filepath.Join(root, "c")))
+
+	assert.Equal(t, []string{"example.com/a", "example.com/b/x"}, w.matchPackages(allPackage, isPackage))
+	assert.Equal(t, []string{"example.com/a", "example.com/b/x", "example.com/lib"}, w.matchPackages("example.com/...", isPackage))
+	dir, ok := w.dir("example.com/b/x")
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(root, "b", "x"), dir)
+	_, ok = w.dir("example.com/c")
+	assert.False(t, ok)
+
+	mod, err := FindModule(filepath.Join(root, "b", "x"))
+	require.NoError(t, err)
+	assert.Equal(t, "1.22", mod.GoVersion)
+}
@@ -1,21 +1,64 @@
 package scopelint
 
-import "go/token"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
 
 // Problem represents a problem in some source code.
+//
+// In JSON, positions are flattened to `filename`, `offset`, `line` and `column`,
+// and the fingerprint is added (see SchemaVersion).
 type Problem struct {
-	Position   token.Position // position in source file
-	Text       string         // the prose that describes the problem
-	Link       string         // (optional) the link to the style guide for the problem
-	Confidence float64        // a value in (0,1] estimating the confidence in this problem's correctness
-	LineText   string         // the source line
-	Category   string         // a short name for the general category of the problem
+	Position   token.Position `json:"-"`          // position in source file
+	End        token.Position `json:"-"`          // end position of the node which has the problem
+	Text       string         `json:"text"`       // the prose that describes the problem
+	Link       string         `json:"link"`       // (optional) the link to the style guide for the problem
+	Confidence float64        `json:"confidence"` // a value in (0,1] estimating the confidence in this problem's correctness
+	LineText   string         `json:"line_text"`  // the source line
+	Category   string         `json:"category"`   // a short name for the general category of the problem
 
 	// If the problem has a suggested fix (the minority case),
 	// ReplacementLine is a full replacement for the relevant line of the source file.
-	ReplacementLine string
+	ReplacementLine string `json:"replacement_line,omitempty"`
 
-	Ignored bool // marks ignored issue by nolint directive
+	Function string `json:"function,omitempty"` // (optional) the name of the function declaration enclosing the problem
+	Variable string `json:"variable,omitempty"` // (optional) the name of the variable causing the problem
+
+	Related []RelatedLocation `json:"related,omitempty"` // (optional) other locations related to the problem, like the loop declaring the variable
+	Fixes   []Fix             `json:"fixes,omitempty"`   // (optional) suggested fixes for the problem
+
+	// BuildContexts are the names of the build contexts like "linux/amd64,integration" which
+	// the file of the problem is built in, when the problem is found in files of several build contexts.
+	BuildContexts []string `json:"build_contexts,omitempty"`
+
+	Ignored   bool       `json:"ignored"`             // marks ignored issue by nolint directive
+	Directive *Directive `json:"directive,omitempty"` // (optional) the directive ignoring the problem
+}
+
+// RelatedLocation is another location related to a problem.
+type RelatedLocation struct {
+	Position token.Position `json:"-"`
+	Message  string         `json:"message"`
+}
+
+// Fix is a suggested fix for a problem.
+type Fix struct {
+	Message string `json:"message"`
+	Edits   []Edit `json:"edits"`
+}
+
+// Edit replaces the source between Position and End with NewText.
+// The positions are not adjusted by //line directives.
+type Edit struct {
+	Position token.Position `json:"-"`
+	End      token.Position `json:"-"`
+	NewText  string         `json:"new_text"`
 }
 
 func (p *Problem) String() string {
@@ -25,6 +68,49 @@ func (p *Problem) String() string {
 	return p.Text
 }
 
+// Fingerprint returns an identifier of the problem which is stable against changes of line numbers.
+// It is made from the filename relative to the module root, the enclosing function, the variable, the category
+// and the line text with normalized spaces.
+func (p *Problem) Fingerprint() string {
+	h := sha256.New()
+	for _, s := range []string{
+		fingerprintFilename(p.Position.Filename),
+		p.Function,
+		p.Variable,
+		p.Category,
+		strings.Join(strings.Fields(p.LineText), " "),
+	} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprintFilename returns the filename relative to the root of the module containing it,
+// or to the working directory out of modules, so that it does not depend on how the file is given.
+func fingerprintFilename(filename string) string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return filepath.ToSlash(filepath.Clean(filename))
+	}
+	root, err := os.Getwd()
+	if mod, merr := FindModule(filepath.Dir(abs)); merr == nil && mod != nil {
+		root, err = mod.Dir, nil
+	}
+	if err == nil {
+		rel, err := filepath.Rel(root, abs)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(abs)
+}
+
+// SortProblems sorts problems by their positions, and then by their texts.
+func SortProblems(problems []Problem) {
+	sort.Stable(problemsByPosition(problems))
+}
+
 type problemsByPosition []Problem
 
 func (p problemsByPosition) Len() int      { return len(p) }
@@ -0,0 +1,70 @@
+package scopelint
+
+import (
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	l := new(Linter)
+	before, err := l.Lint("mypkg/mypkg.go", []byte(`package main
+
+func (*T) factory() (ret func() *int) {
+	for _, i := range make([]int, 1) {
+		ret = func() *int { return &i }
+	}
+	return
+}`))
+	require.NoError(t, err)
+	require.Len(t, before, 2)
+	assert.Equal(t, "(T).factory", before[0].Function)
+	assert.Equal(t, "i", before[0].Variable)
+
+	after, err := l.Lint("mypkg/mypkg.go", []byte(`package main
+
+type T struct{}
+
+func (*T) factory() (ret func() *int) {
+	for _, i := range make([]int, 1) {
+		ret = func() *int {   return &i }
+	}
+	return
+}`))
+	require.NoError(t, err)
+	require.Len(t, after, 2)
+	assert.NotEqual(t, before[0].Position.Line, after[0].Position.Line)
+	assert.Equal(t, before[0].Fingerprint(), after[0].Fingerprint())
+	assert.Equal(t, before[1].Fingerprint(), after[1].Fingerprint())
+	assert.Equal(t, after[0].Fingerprint(), after[1].Fingerprint(), "problems in a line for a variable are not distinguished")
+
+	renamed, err := l.Lint("mypkg/mypkg.go", []byte(`package main
+
+func (*T) build() (ret func() *int) {
+	for _, i := range make([]int, 1) {
+		ret = func() *int { return &i }
+	}
+	return
+}`))
+	require.NoError(t, err)
+	require.Len(t, renamed, 2)
+	assert.NotEqual(t, before[0].Fingerprint(), renamed[0].Fingerprint())
+}
+
+func TestFingerprintFilename(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	fingerprint := func(filename string) string {
+		p := Problem{Position: token.Position{Filename: filename}, Category: "range-scope", Variable: "v"}
+		return p.Fingerprint()
+	}
+	assert.Equal(t, fingerprint("x.go"), fingerprint("./x.go"))
+	assert.Equal(t, fingerprint("x.go"), fingerprint(filepath.Join(wd, "x.go")))
+	assert.Equal(t, fingerprint("x.go"), fingerprint("../scopelint/x.go"))
+	assert.NotEqual(t, fingerprint("x.go"), fingerprint("y.go"))
+	assert.Equal(t, "scopelint/x.go", fingerprintFilename("./x.go"), "filenames are relative to the module root")
+}
@@ -0,0 +1,21 @@
+package scopelint
+
+// Rule describes a category of problems.
+type Rule struct {
+	Category    string // a short name of the category
+	Description string // the prose that describes the category
+	Link        string // (optional) the link to the document for the category
+}
+
+var rules = []Rule{
+	{
+		Category:    "range-scope",
+		Description: "Using a variable declared in a loop by reference or in a function literal, which may be shared by all iterations",
+		Link:        "https://github.com/kyoh86/scopelint#whats-this",
+	},
+}
+
+// Rules returns the descriptions of the categories of problems.
+func Rules() []Rule {
+	return append([]Rule{}, rules...)
+}
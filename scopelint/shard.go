@@ -0,0 +1,64 @@
+package scopelint
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Shard is a part of packages partitioned by a stable hash of their import paths (or directories),
+// to lint them on several machines. Index is from 1 to Count.
+//
+// A package belongs to the same shard wherever and whenever it is linted,
+// regardless of the other packages, so shards of a run cover all the packages without overlaps.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// ParseShard parses a shard in the form of "i/n", like "2/4".
+func ParseShard(s string) (Shard, error) {
+	i := strings.IndexByte(s, '/')
+	if i < 0 {
+		return Shard{}, fmt.Errorf("invalid shard %q: want INDEX/COUNT", s)
+	}
+	index, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return Shard{}, fmt.Errorf("invalid shard %q: want INDEX/COUNT", s)
+	}
+	count, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return Shard{}, fmt.Errorf("invalid shard %q: want INDEX/COUNT", s)
+	}
+	if count < 1 || index < 1 || index > count {
+		return Shard{}, fmt.Errorf("invalid shard %q: INDEX must be from 1 to COUNT", s)
+	}
+	return Shard{Index: index, Count: count}, nil
+}
+
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// Contains reports whether the package of the import path or the directory belongs to the shard.
+func (s Shard) Contains(name string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(filepath.ToSlash(name)))
+	return int(h.Sum32()%uint32(s.Count)) == s.Index-1
+}
+
+// Filter returns the names of packages which belong to the shard, in the same order.
+func (s Shard) Filter(names []string) []string {
+	var filtered []string
+	for _, name := range names {
+		if s.Contains(name) {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,46 @@
+package scopelint
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseShard(t *testing.T) {
+	shard, err := ParseShard("2/4")
+	require.NoError(t, err)
+	assert.Equal(t, Shard{Index: 2, Count: 4}, shard)
+	assert.Equal(t, "2/4", shard.String())
+
+	for _, s := range []string{"", "2", "a/4", "2/b", "0/4", "5/4", "1/0"} {
+		_, err := ParseShard(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestShard(t *testing.T) {
+	var names []string
+	for i := 0; i < 100; i++ {
+		names = append(names, fmt.Sprintf("example.com/m/p%02d", i))
+	}
+	seen := map[string]int{}
+	for index := 1; index <= 3; index++ {
+		shard := Shard{Index: index, Count: 3}
+		filtered := shard.Filter(names)
+		assert.NotEmpty(t, filtered)
+		assert.Equal(t, filtered, shard.Filter(names), "shards are deterministic")
+		assert.Equal(t, filtered[:1], shard.Filter(filtered[:1]), "a package belongs to a shard regardless of the other packages")
+		for _, name := range filtered {
+			seen[name]++
+		}
+	}
+	assert.Len(t, seen, len(names), "shards cover all packages")
+	for name, n := range seen {
+		assert.Equal(t, 1, n, "%s is in a shard", name)
+	}
+
+	// The hash is fixed, so that shards are the same on every machine.
+	assert.Equal(t, []string{"example.com/m/p00", "example.com/m/p03"}, (Shard{Index: 1, Count: 3}).Filter(names[:5]))
+}
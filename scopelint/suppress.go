@@ -0,0 +1,245 @@
+package scopelint
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// suppressPrefix is the prefix of the directive inserted by Suppress.
+const suppressPrefix = "//scopelint:ignore // TODO(scopelint): "
+
+// Suppress inserts ignore directives for every active problem in a set of files of a single package.
+// The argument is a map of filename to source.
+//
+// A directive is put on the end of the line which has the problem.
+// If the directive does not hide the problem there (e.g. the line ends in a multi-line statement),
+// another one is put on the beginning of a line before the statement containing the problem.
+// Files formatted by gofmt keep formatted.
+//
+// It returns a map of filename to new source of the changed files,
+// and the problems which could not be suppressed.
+func (l *Linter) Suppress(files map[string][]byte) (map[string][]byte, []Problem, error) {
+	return l.SuppressFunc(files, nil)
+}
+
+// SuppressFunc is like Suppress, but inserts directives only for the problems for which selected returns true,
+// like the ones in the enabled categories. If selected is nil, all problems are selected.
+func (l *Linter) SuppressFunc(files map[string][]byte, selected func(Problem) bool) (map[string][]byte, []Problem, error) {
+	problems, err := l.lintSelected(files, selected)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines := activeProblems(problems, func(p Problem) int { return p.Position.Line })
+
+	// Try directives on the end of the lines, and leave only ones that hide the problems.
+	sources := make(map[string][]byte, len(files))
+	for filename, src := range files {
+		sources[filename] = appendDirectives(src, lines[filename])
+	}
+	problems, err = l.lintSelected(sources, selected)
+	if err != nil {
+		return nil, nil, err
+	}
+	for filename, failed := range activeProblems(problems, func(p Problem) int { return p.Position.Line }) {
+		for line := range failed {
+			delete(lines[filename], line)
+		}
+		sources[filename] = appendDirectives(files[filename], lines[filename])
+	}
+
+	problems, err = l.lintSelected(sources, selected)
+	if err != nil {
+		return nil, nil, err
+	}
+	for filename, offsets := range activeProblems(problems, func(p Problem) int { return p.Position.Offset }) {
+		src, err := insertDirectives(filename, sources[filename], offsets)
+		if err != nil {
+			return nil, nil, err
+		}
+		sources[filename] = src
+	}
+
+	problems, err = l.lintSelected(sources, selected)
+	if err != nil {
+		return nil, nil, err
+	}
+	var remaining []Problem
+	for _, p := range problems {
+		if !p.Ignored {
+			remaining = append(remaining, p)
+		}
+	}
+
+	changed := map[string][]byte{}
+	for filename, src := range sources {
+		orig := files[filename]
+		if bytes.Equal(src, orig) {
+			continue
+		}
+		if formatted, err := format.Source(orig); err == nil && bytes.Equal(formatted, orig) {
+			if formatted, err := format.Source(src); err == nil {
+				src = formatted
+			}
+		}
+		changed[filename] = src
+	}
+	return changed, remaining, nil
+}
+
+// lintSelected lints the files, and returns the problems for which selected returns true.
+func (l *Linter) lintSelected(files map[string][]byte, selected func(Problem) bool) ([]Problem, error) {
+	problems, err := l.LintFiles(files)
+	if err != nil || selected == nil {
+		return problems, err
+	}
+	var filtered []Problem
+	for _, p := range problems {
+		if selected(p) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
+}
+
+// activeProblems groups the messages of active problems by filename and the key.
+func activeProblems(problems []Problem, key func(Problem) int) map[string]map[int][]string {
+	grouped := map[string]map[int][]string{}
+	for _, p := range problems {
+		if p.Ignored {
+			continue
+		}
+		messages, ok := grouped[p.Position.Filename]
+		if !ok {
+			messages = map[int][]string{}
+			grouped[p.Position.Filename] = messages
+		}
+		k := key(p)
+		if !containsString(messages[k], p.Text) {
+			messages[k] = append(messages[k], p.Text)
+		}
+	}
+	return grouped
+}
+
+func containsString(list []string, needle string) bool {
+	for _, s := range list {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
+
+// appendDirectives puts directives on the end of the lines.
+// The argument messages is a map of line number to the messages of the problems.
+// Lines which end in multi-line tokens (i.e. raw strings or general comments) are skipped.
+func appendDirectives(src []byte, messages map[int][]string) []byte {
+	if len(messages) == 0 {
+		return src
+	}
+	unsafe := multiLineTokenLines(src)
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	for i, line := range lines {
+		ms, ok := messages[i+1]
+		if !ok || unsafe[i+1] {
+			continue
+		}
+		body := bytes.TrimRight(line, "\r\n")
+		eol := line[len(body):]
+		lines[i] = append(append(append([]byte{}, body...), " "+suppressPrefix+strings.Join(ms, "; ")...), eol...)
+	}
+	return bytes.Join(lines, nil)
+}
+
+// multiLineTokenLines returns the line numbers which end in tokens spanning multiple lines.
+func multiLineTokenLines(src []byte) map[int]bool {
+	unsafe := map[int]bool{}
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if (tok != token.STRING && tok != token.COMMENT) || !strings.Contains(lit, "\n") {
+			continue
+		}
+		start := fset.Position(pos).Line
+		for l := start; l < start+strings.Count(lit, "\n"); l++ {
+			unsafe[l] = true
+		}
+	}
+	return unsafe
+}
+
+// insertDirectives puts directives on the beginning of lines before the innermost statements
+// which start on the beginning of lines and contain the problems.
+// The argument messages is a map of offset to the messages of the problems.
+func insertDirectives(filename string, src []byte, messages map[int][]string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	inserts := map[int][]string{} // offset of the beginning of the line => messages
+	for offset, ms := range messages {
+		offset := offset
+		var stmt ast.Stmt
+		ast.Inspect(file, func(node ast.Node) bool {
+			if node == nil {
+				return false
+			}
+			start, end := fset.Position(node.Pos()).Offset, fset.Position(node.End()).Offset
+			if offset < start || end <= offset {
+				return false
+			}
+			if s, ok := node.(ast.Stmt); ok && startsLine(src, start) {
+				stmt = s
+			}
+			return true
+		})
+		if stmt == nil {
+			continue
+		}
+		begin := lineStart(src, fset.Position(stmt.Pos()).Offset)
+		for _, m := range ms {
+			if !containsString(inserts[begin], m) {
+				inserts[begin] = append(inserts[begin], m)
+			}
+		}
+	}
+
+	offsets := make([]int, 0, len(inserts))
+	for offset := range inserts {
+		offsets = append(offsets, offset)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+	for _, offset := range offsets {
+		indent := leadingSpaces(src[offset:])
+		line := string(indent) + suppressPrefix + strings.Join(inserts[offset], "; ") + "\n"
+		src = append(src[:offset:offset], append([]byte(line), src[offset:]...)...)
+	}
+	return src, nil
+}
+
+// startsLine reports whether only spaces are put before the offset in the line.
+func startsLine(src []byte, offset int) bool {
+	return len(bytes.TrimSpace(src[lineStart(src, offset):offset])) == 0
+}
+
+func lineStart(src []byte, offset int) int {
+	return bytes.LastIndexByte(src[:offset], '\n') + 1
+}
+
+func leadingSpaces(line []byte) []byte {
+	return line[:len(line)-len(bytes.TrimLeft(line, " \t"))]
+}
@@ -0,0 +1,118 @@
+package scopelint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuppress(t *testing.T) {
+	t.Run("end of line", func(t *testing.T) {
+		l := new(Linter)
+		changed, remaining, err := l.Suppress(map[string][]byte{"mypkg/mypkg.go": []byte(`package main
+
+func main() {
+	var copies []*string
+	for _, val := range []string{"a"} {
+		copies = append(copies, &val)
+		println(len(copies)) // comment
+	}
+}
+`)})
+		require.NoError(t, err)
+		assert.Empty(t, remaining)
+		assert.Equal(t, `package main
+
+func main() {
+	var copies []*string
+	for _, val := range []string{"a"} {
+		copies = append(copies, &val) //scopelint:ignore // TODO(scopelint): Using a reference for the variable on range scope "val"
+		println(len(copies))          // comment
+	}
+}
+`, string(changed["mypkg/mypkg.go"]))
+	})
+
+	t.Run("before statement", func(t *testing.T) {
+		l := new(Linter)
+		changed, remaining, err := l.Suppress(map[string][]byte{"mypkg/mypkg.go": []byte(`package main
+
+import "testing"
+
+func TestSomething(t *testing.T) {
+	for _, tc := range []struct {
+		expected string
+	}{} {
+		t.Run("sub", func(t *testing.T) {
+			if "result" != tc.expected {
+				t.Fatal("failed")
+			}
+		})
+	}
+}
+`)})
+		require.NoError(t, err)
+		assert.Empty(t, remaining)
+		assert.Equal(t, `package main
+
+import "testing"
+
+func TestSomething(t *testing.T) {
+	for _, tc := range []struct {
+		expected string
+	}{} {
+		t.Run("sub", func(t *testing.T) {
+			//scopelint:ignore // TODO(scopelint): Using the variable on range scope "tc" in function literal
+			if "result" != tc.expected {
+				t.Fatal("failed")
+			}
+		})
+	}
+}
+`, string(changed["mypkg/mypkg.go"]))
+	})
+
+	t.Run("idempotent", func(t *testing.T) {
+		l := new(Linter)
+		files := map[string][]byte{"mypkg/mypkg.go": []byte(`package main
+
+func main() {
+	var copies []*string
+	for _, val := range []string{"a"} {
+		copies = append(copies, &val)
+	}
+}
+`)}
+		changed, _, err := l.Suppress(files)
+		require.NoError(t, err)
+		require.Len(t, changed, 1)
+
+		changed, remaining, err := l.Suppress(changed)
+		require.NoError(t, err)
+		assert.Empty(t, remaining)
+		assert.Empty(t, changed)
+	})
+}
+
+func TestSuppressFunc(t *testing.T) {
+	files := map[string][]byte{"mypkg/mypkg.go": []byte(`package main
+
+func main() {
+	var copies []*string
+	for _, val := range []string{"a"} {
+		copies = append(copies, &val)
+	}
+}
+`)}
+	l := new(Linter)
+	changed, remaining, err := l.SuppressFunc(files, func(p Problem) bool { return p.Category != "range-scope" })
+	require.NoError(t, err)
+	assert.Empty(t, changed, "unselected problems are not suppressed")
+	assert.Empty(t, remaining)
+
+	changed, remaining, err = l.SuppressFunc(files, func(p Problem) bool { return true })
+	require.NoError(t, err)
+	assert.Len(t, changed, 1)
+	assert.Empty(t, remaining)
+}
@@ -0,0 +1,230 @@
+package scopelint
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// workFilename is the name of the file defining a Go workspace.
+const workFilename = "go.work"
+
+// Settings of the go.work file for ModuleLoader.Workspace other than its path.
+const (
+	WorkspaceAuto = "auto" // find go.work upward from the working directory
+	WorkspaceOff  = "off"  // do not use go.work
+)
+
+// workspace is the set of modules where packages are resolved:
+// the modules used in a go.work file, or the main module.
+type workspace struct {
+	File    string            // go.work file, or empty for the main module
+	Modules []*Module         // modules in the workspace
+	Replace map[string]string // module paths replaced with local directories in go.work
+}
+
+// moduleRoot is a pair of a module path and the directory of its root package.
+type moduleRoot struct {
+	path string
+	dir  string
+}
+
+// findWorkFile returns the go.work file for the working directory, or an empty string if it is not in a workspace.
+// The setting is "auto" (or empty), "off" or the path of go.work, like the GOWORK environment variable,
+// which is followed if the setting is "auto".
+func findWorkFile(setting string) (string, error) {
+	for _, work := range []string{setting, os.Getenv("GOWORK")} {
+		switch work {
+		case WorkspaceOff:
+			return "", nil
+		case "", WorkspaceAuto:
+		default:
+			return work, nil
+		}
+	}
+	dir, err := filepath.Abs(".")
+	if err != nil {
+		return "", err
+	}
+	for {
+		if filename := filepath.Join(dir, workFilename); isFileExists(filename) {
+			return filename, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// readWorkspace reads the go.work file.
+func readWorkspace(filename string) (*workspace, error) {
+	filename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(filename)
+	w := &workspace{File: filename, Replace: map[string]string{}}
+	if err := readModFile(filename, func(verb string, args []string) error {
+		if err := unquoteArgs(args); err != nil {
+			return err
+		}
+		switch verb {
+		case "use":
+			if len(args) != 1 {
+				return fmt.Errorf("usage: use local/dir")
+			}
+			mod, err := readModule(absPath(dir, args[0]))
+			if err != nil {
+				return err
+			}
+			w.Modules = append(w.Modules, mod)
+		case "replace":
+			return readReplace(dir, args, w.Replace)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// findWorkspace returns the workspace of the working directory:
+// the go.work file, or the module containing the working directory.
+// It returns nil if the working directory is in neither of them.
+func findWorkspace(setting string) (*workspace, error) {
+	filename, err := findWorkFile(setting)
+	if err != nil {
+		return nil, err
+	}
+	if filename != "" {
+		return readWorkspace(filename)
+	}
+	mod, err := FindModule(".")
+	if err != nil || mod == nil {
+		return nil, err
+	}
+	return &workspace{Modules: []*Module{mod}}, nil
+}
+
+// modules returns the roots of the modules in the workspace.
+func (w *workspace) modules() []moduleRoot {
+	var roots []moduleRoot
+	for _, mod := range w.Modules {
+		roots = append(roots, moduleRoot{path: mod.Path, dir: mod.Dir})
+	}
+	return roots
+}
+
+// roots returns the roots of the modules in the workspace and the local replacements.
+// Replacements in go.work override ones in go.mod, and the modules in the workspace override both.
+func (w *workspace) roots() []moduleRoot {
+	dirs := map[string]string{}
+	for _, mod := range w.Modules {
+		for p, dir := range mod.Replace {
+			dirs[p] = dir
+		}
+	}
+	for p, dir := range w.Replace {
+		dirs[p] = dir
+	}
+	for _, mod := range w.Modules {
+		dirs[mod.Path] = mod.Dir
+	}
+	roots := make([]moduleRoot, 0, len(dirs))
+	for p, dir := range dirs {
+		roots = append(roots, moduleRoot{path: p, dir: dir})
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].path < roots[j].path })
+	return roots
+}
+
+// used reports whether the directory is the root of a module in the workspace.
+func (w *workspace) used(dir string) bool {
+	for _, mod := range w.Modules {
+		if mod.Dir == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// dir returns the directory of the package in the workspace or the local replacements.
+// It returns false if the package is out of them, or in a nested module out of the workspace.
+func (w *workspace) dir(importPath string) (string, bool) {
+	var found moduleRoot
+	for _, root := range w.roots() {
+		if hasPathPrefix(importPath, root.path) && len(root.path) > len(found.path) {
+			found = root
+		}
+	}
+	if found.path == "" {
+		return "", false
+	}
+	dir := found.dir
+	for _, elem := range strings.Split(strings.TrimPrefix(importPath[len(found.path):], "/"), "/") {
+		if elem == "" {
+			continue
+		}
+		dir = filepath.Join(dir, elem)
+		if isFileExists(filepath.Join(dir, modFilename)) {
+			return "", false
+		}
+	}
+	return dir, true
+}
+
+// matchPackages returns the import paths of the packages matching the pattern
+// in the workspace and the local replacements. The pattern "all" matches the packages in the workspace.
+// Nested modules are not walked into, and directories are matched only if isPackage reports true.
+func (w *workspace) matchPackages(pattern string, isPackage func(dir string) bool) []string {
+	match := func(string) bool { return true }
+	treeCanMatch := func(string) bool { return true }
+	roots := w.modules()
+	if pattern != allPackage {
+		match = matchPattern(pattern)
+		treeCanMatch = treeCanMatchPattern(pattern)
+		roots = w.roots()
+	}
+
+	var pkgs []string
+	for _, root := range roots {
+		root := root
+		if !treeCanMatch(root.path) {
+			continue
+		}
+		if err := filepath.Walk(root.dir, func(dir string, fi os.FileInfo, err error) error {
+			if err != nil || !fi.IsDir() {
+				return nil
+			}
+			name := root.path
+			if dir != root.dir {
+				// Avoid .foo, _foo, and testdata directory trees, and nested modules.
+				elem := fi.Name()
+				if strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") || elem == "testdata" ||
+					isFileExists(filepath.Join(dir, modFilename)) {
+					return filepath.SkipDir
+				}
+				name = path.Join(root.path, filepath.ToSlash(dir[len(root.dir)+1:]))
+			}
+			if !treeCanMatch(name) {
+				return filepath.SkipDir
+			}
+			if !match(name) {
+				return nil
+			}
+			if !isPackage(dir) {
+				return nil
+			}
+			pkgs = append(pkgs, name)
+			return nil
+		}); err != nil {
+			panic(err)
+		}
+	}
+	return pkgs
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// suppression is an ignore directive listed by the `suppressions` command.
+type suppression struct {
+	Filename      string   `json:"filename"`
+	Line          int      `json:"line"`
+	Scope         string   `json:"scope"`
+	Rules         []string `json:"rules"`
+	Justification string   `json:"justification"`
+	Expires       string   `json:"expires,omitempty"`
+	Expired       bool     `json:"expired"`
+	Hidden        int      `json:"hidden"`
+}
+
+func listSuppressions() {
+	suppressions := []suppression{}
+	eachPackage(func(pkg *scopelint.LoadedPackage) {
+		cfg, err := loadConfig(pkg.Dir)
+		if err != nil {
+			runErrors.add(newLintError(pkg.Name, err, true))
+			return
+		}
+		directives, err := cfg.linter(pkg).Directives(pkg.Files)
+		if err != nil {
+			runErrors.add(newLintError(pkg.Name, err, false))
+			return
+		}
+		for _, d := range directives {
+			s := suppression{
+				Filename:      d.Position.Filename,
+				Line:          d.Position.Line,
+				Scope:         string(d.Scope),
+				Rules:         d.Rules,
+				Justification: d.Justification,
+				Expired:       d.Expired(time.Now()),
+				Hidden:        d.Hidden,
+			}
+			if s.Rules == nil {
+				s.Rules = []string{}
+			}
+			if !d.Expires.IsZero() {
+				s.Expires = d.Expires.Format("2006-01-02")
+			}
+			suppressions = append(suppressions, s)
+		}
+	})
+
+	switch params.suppressionsFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(suppressions); err != nil {
+			fatal(err)
+		}
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "FILE\tLINE\tSCOPE\tRULES\tEXPIRES\tHIDDEN\tJUSTIFICATION")
+		for _, s := range suppressions {
+			rules := strings.Join(s.Rules, ",")
+			if rules == "" {
+				rules = "*"
+			}
+			expires := s.Expires
+			switch {
+			case expires == "":
+				expires = "-"
+			case s.Expired:
+				expires += " (expired)"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%d\t%s\n", s.Filename, s.Line, s.Scope, rules, expires, s.Hidden, s.Justification)
+		}
+		w.Flush()
+	}
+	finish(0, runErrors.list())
+}
+
+func suppress() {
+	eachPackage(func(pkg *scopelint.LoadedPackage) {
+		cfg, err := loadConfig(pkg.Dir)
+		if err != nil {
+			runErrors.add(newLintError(pkg.Name, err, true))
+			return
+		}
+		// Problems in the disabled categories are not suppressed.
+		changed, remaining, err := cfg.linter(pkg).SuppressFunc(pkg.Files, func(p scopelint.Problem) bool {
+			return cfg.enabled(p.Category)
+		})
+		if err != nil {
+			runErrors.add(newLintError(pkg.Name, err, false))
+			return
+		}
+		for filename, src := range changed {
+			if err := writeFile(filename, src); err != nil {
+				runErrors.add(newLintError(pkg.Name, err, true))
+				continue
+			}
+			fmt.Println(filename)
+		}
+		for _, p := range remaining {
+			fmt.Fprintf(os.Stderr, "%v: could not suppress: %s\n", p.Position, p.Text)
+		}
+	})
+	finish(0, runErrors.list())
+}
+
+// writeFile writes the source to the file keeping its permission.
+func writeFile(filename string, src []byte) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, src, info.Mode().Perm())
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+const (
+	templateFormat = "template"
+	templatePrefix = templateFormat + "="
+)
+
+// templateReporter reports problems with a user-defined text/template.
+//
+// The "problem" template (or the main template if it is not defined) is executed for each problem,
+// and the optional "header" and "footer" templates are executed with a templateSummary
+// before and after all problems.
+// All problems are buffered so that the header can refer to the summary.
+type templateReporter struct {
+	w           io.Writer
+	tmpl        *template.Template
+	newline     bool // whether a newline is written after each problem
+	showIgnored bool
+	summary     templateSummary
+	problems    []scopelint.Problem
+}
+
+// templateSummary is the data for the header and footer templates.
+type templateSummary struct {
+	Tool     jsonTool
+	Packages []string
+	Errors   []lintError
+	Totals   jsonTotals
+}
+
+var templateFuncs = template.FuncMap{
+	"relative": func(filename string) string {
+		rel, _ := relativePath(filename)
+		return rel
+	},
+	"json": func(v interface{}) (string, error) {
+		raw, err := json.Marshal(v)
+		return string(raw), err
+	},
+	"join":     strings.Join,
+	"color":    colorize,
+	"severity": problemSeverity,
+}
+
+// newTemplateReporter creates a reporter for the format "template=TEXT",
+// or "template" with the template file.
+func newTemplateReporter(w io.Writer, format, file string, showIgnored bool) (*templateReporter, error) {
+	r := &templateReporter{
+		w:           w,
+		showIgnored: showIgnored,
+		summary:     templateSummary{Tool: tool},
+	}
+	color := colorEnabled(w)
+	tmpl := template.New(templateFormat).Funcs(templateFuncs).Funcs(template.FuncMap{
+		"color": func(names, text string) string {
+			if !color {
+				return text
+			}
+			return colorize(names, text)
+		},
+	})
+	var err error
+	switch {
+	case file != "":
+		var text []byte
+		text, err = ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		r.tmpl, err = tmpl.Parse(string(text))
+	case strings.HasPrefix(format, templatePrefix):
+		r.newline = true
+		r.tmpl, err = tmpl.Parse(strings.TrimPrefix(format, templatePrefix))
+	default:
+		return nil, fmt.Errorf("format %q needs a template like %s{{.Text}} or the --template-file flag", format, templatePrefix)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %v", err)
+	}
+	return r, nil
+}
+
+func (r *templateReporter) Report(pkg string, problems []scopelint.Problem) error {
+	r.summary.Packages = append(r.summary.Packages, pkg)
+	r.summary.Totals.count(problems)
+	for _, p := range problems {
+		if p.Ignored && !r.showIgnored {
+			continue
+		}
+		r.problems = append(r.problems, p)
+	}
+	return nil
+}
+
+func (r *templateReporter) ReportError(e lintError) error {
+	r.summary.Errors = append(r.summary.Errors, e)
+	r.summary.Totals.Errors++
+	return nil
+}
+
+func (r *templateReporter) Close() error {
+	if err := r.section("header", r.summary); err != nil {
+		return err
+	}
+	problem := r.tmpl
+	if t := r.tmpl.Lookup("problem"); t != nil {
+		problem = t
+	}
+	for _, p := range r.problems {
+		if err := problem.Execute(r.w, p); err != nil {
+			return err
+		}
+		if r.newline {
+			if _, err := fmt.Fprintln(r.w); err != nil {
+				return err
+			}
+		}
+	}
+	return r.section("footer", r.summary)
+}
+
+// section executes the named template if it is defined.
+func (r *templateReporter) section(name string, data interface{}) error {
+	t := r.tmpl.Lookup(name)
+	if t == nil {
+		return nil
+	}
+	return t.Execute(r.w, data)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kyoh86/scopelint/scopelint"
+	isatty "github.com/mattn/go-isatty"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// isTerminal reports whether the writer is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()))
+}
+
+// colorEnabled reports whether outputs to the writer should be colored, following the --color flag.
+func colorEnabled(w io.Writer) bool {
+	switch params.color {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && isTerminal(w)
+}
+
+// textReporter reports problems in lines like `file:line:column: text`.
+//
+// If frames is true, each problem is followed by a code frame which shows the source line
+// with a caret under the column, the loop declaring the variable, and the suggested pin.
+type textReporter struct {
+	w           io.Writer
+	showIgnored bool
+	frames      bool
+	context     int  // number of source lines shown around the problem in frames
+	color       bool // whether to decorate with ANSI colors
+	sources     sourceCache
+}
+
+func newTextReporter(w io.Writer, showIgnored bool) *textReporter {
+	return &textReporter{
+		w:           w,
+		showIgnored: showIgnored,
+		frames:      params.contextSet || isTerminal(w),
+		context:     params.context,
+		color:       colorEnabled(w),
+		sources:     sourceCache{},
+	}
+}
+
+func (r *textReporter) Report(_ string, problems []scopelint.Problem) error {
+	for _, p := range problems {
+		if p.Ignored && !r.showIgnored {
+			continue
+		}
+		if err := r.report(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *textReporter) Close() error { return nil }
+
+func (r *textReporter) report(p scopelint.Problem) error {
+	var marker string
+	if len(p.BuildContexts) > 0 {
+		marker += " " + r.colorize("faint", "["+strings.Join(p.BuildContexts, " ")+"]")
+	}
+	if p.Ignored {
+		marker += " " + r.colorize("faint", "(ignored)")
+	}
+	if !r.frames {
+		_, err := fmt.Fprintf(r.w, "%v: %s%s\n", p.Position, p.Text, marker)
+		return err
+	}
+	_, err := fmt.Fprintf(r.w, "%s: %s: %s%s\n",
+		r.colorize("bold", p.Position.String()),
+		r.colorize("bold "+severityColor(p), p.Category),
+		p.Text, marker,
+	)
+	if err != nil {
+		return err
+	}
+	return r.frame(p)
+}
+
+// frame writes the code frame of the problem.
+func (r *textReporter) frame(p scopelint.Problem) error {
+	lines := r.sources.lines(p.Position.Filename)
+	if p.Position.Line < 1 || p.Position.Line > len(lines) {
+		if p.LineText == "" {
+			return nil
+		}
+		lines = nil
+	}
+	line := func(n int) string {
+		if lines == nil {
+			return p.LineText
+		}
+		return lines[n-1]
+	}
+	first, last := p.Position.Line-r.context, p.Position.Line+r.context
+	if first < 1 {
+		first = 1
+	}
+	if last > len(lines) {
+		last = len(lines)
+	}
+	if lines == nil {
+		first, last = p.Position.Line, p.Position.Line
+	}
+
+	var loop *scopelint.RelatedLocation
+	for i, related := range p.Related {
+		if related.Position.Filename == p.Position.Filename && related.Position.Line >= 1 && related.Position.Line < first && lines != nil {
+			loop = &p.Related[i]
+			break
+		}
+	}
+
+	width := len(fmt.Sprint(last))
+	gutter := func(n int) string {
+		if n == 0 {
+			return r.colorize("blue", strings.Repeat(" ", width)+" |")
+		}
+		return r.colorize("blue", fmt.Sprintf("%*d |", width, n))
+	}
+	var b strings.Builder
+	if loop != nil {
+		fmt.Fprintf(&b, "%s %s\n", gutter(loop.Position.Line), line(loop.Position.Line))
+		fmt.Fprintf(&b, "%s %s %s\n", gutter(0), caretPadding(line(loop.Position.Line), loop.Position.Column), r.colorize("blue", "- "+loop.Message))
+		if loop.Position.Line+1 < first {
+			fmt.Fprintf(&b, "%s\n", r.colorize("blue", strings.Repeat(".", width+2)))
+		}
+	}
+	for n := first; n <= last; n++ {
+		fmt.Fprintf(&b, "%s %s\n", gutter(n), line(n))
+		if n != p.Position.Line {
+			continue
+		}
+		carets := 1
+		if p.End.Line == p.Position.Line && p.End.Column > p.Position.Column {
+			carets = utf8.RuneCountInString(safeSlice(line(n), p.Position.Column-1, p.End.Column-1))
+		}
+		fmt.Fprintf(&b, "%s %s%s\n", gutter(0), caretPadding(line(n), p.Position.Column), r.colorize("bold "+severityColor(p), strings.Repeat("^", carets)))
+	}
+	if !p.Ignored {
+		for _, fix := range p.Fixes {
+			var pins []string
+			for _, edit := range fix.Edits {
+				pins = append(pins, strings.TrimSpace(edit.NewText))
+			}
+			fmt.Fprintf(&b, "%s %s %s: %s\n", gutter(0), r.colorize("bold green", "help:"), fix.Message, r.colorize("green", strings.Join(pins, "; ")))
+		}
+	}
+	_, err := io.WriteString(r.w, b.String())
+	return err
+}
+
+func (r *textReporter) colorize(names, text string) string {
+	if !r.color {
+		return text
+	}
+	return colorize(names, text)
+}
+
+// caretPadding returns the whitespace which puts the next character under the column of the line.
+// Tabs are kept so that the padding is aligned with the line in any tab width.
+func caretPadding(line string, column int) string {
+	var b strings.Builder
+	for _, c := range safeSlice(line, 0, column-1) {
+		if c == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return b.String()
+}
+
+// safeSlice returns s[start:end] clipped to the bounds of s.
+func safeSlice(s string, start, end int) string {
+	if end > len(s) {
+		end = len(s)
+	}
+	if start > end {
+		start = end
+	}
+	if start < 0 {
+		start = 0
+	}
+	return s[start:end]
+}
+
+func severityColor(p scopelint.Problem) string {
+	if p.Ignored {
+		return "gray"
+	}
+	switch problemSeverity(p) {
+	case severityWarning:
+		return "yellow"
+	case severityInfo:
+		return "cyan"
+	}
+	return "red"
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/kyoh86/scopelint/scopelint"
+)
+
+// xmlReporter streams elements in a root element.
+// The root element is opened on the first report, and closed on Close.
+type xmlReporter struct {
+	w       io.Writer
+	root    string // the start tag of the root element
+	started bool
+}
+
+func (r *xmlReporter) start() error {
+	if r.started {
+		return nil
+	}
+	r.started = true
+	_, err := fmt.Fprintf(r.w, "%s%s\n", xml.Header, r.root)
+	return err
+}
+
+func (r *xmlReporter) encode(element interface{}) error {
+	if err := r.start(); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(r.w)
+	enc.Indent("  ", "  ")
+	if err := enc.Encode(element); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(r.w)
+	return err
+}
+
+func (r *xmlReporter) end(name string) error {
+	if err := r.start(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(r.w, "</%s>\n", name)
+	return err
+}
+
+// checkstyleReporter reports problems in Checkstyle XML.
+type checkstyleReporter struct {
+	xmlReporter
+	showIgnored bool
+}
+
+func newCheckstyleReporter(w io.Writer, showIgnored bool) *checkstyleReporter {
+	return &checkstyleReporter{
+		xmlReporter: xmlReporter{w: w, root: `<checkstyle version="5.0">`},
+		showIgnored: showIgnored,
+	}
+}
+
+type checkstyleFile struct {
+	XMLName xml.Name          `xml:"file"`
+	Name    string            `xml:"name,attr"`
+	Errors  []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+func (r *checkstyleReporter) Report(_ string, problems []scopelint.Problem) error {
+	if err := r.start(); err != nil {
+		return err
+	}
+	var file *checkstyleFile
+	for _, p := range problems {
+		severity, message := problemSeverity(p), p.Text
+		if p.Ignored {
+			if !r.showIgnored {
+				continue
+			}
+			severity, message = severityInfo, message+" (ignored)"
+		}
+		if file != nil && file.Name != p.Position.Filename {
+			if err := r.encode(file); err != nil {
+				return err
+			}
+			file = nil
+		}
+		if file == nil {
+			file = &checkstyleFile{Name: p.Position.Filename}
+		}
+		file.Errors = append(file.Errors, checkstyleError{
+			Line:     p.Position.Line,
+			Column:   p.Position.Column,
+			Severity: severity,
+			Message:  message,
+			Source:   "scopelint." + p.Category,
+		})
+	}
+	if file != nil {
+		return r.encode(file)
+	}
+	return nil
+}
+
+// ReportError reports an error in a file element named by the package of the error.
+func (r *checkstyleReporter) ReportError(e lintError) error {
+	name := e.Package
+	if name == "" {
+		name = "scopelint"
+	}
+	return r.encode(&checkstyleFile{Name: name, Errors: []checkstyleError{{
+		Severity: severityError,
+		Message:  e.Message,
+		Source:   "scopelint." + e.Class,
+	}}})
+}
+
+func (r *checkstyleReporter) Close() error {
+	return r.end("checkstyle")
+}
+
+// junitReporter reports problems in JUnit XML.
+// Each package is a test suite which has a test case, and each active problem is a failure of it.
+type junitReporter struct {
+	xmlReporter
+}
+
+func newJUnitReporter(w io.Writer) *junitReporter {
+	return &junitReporter{
+		xmlReporter: xmlReporter{w: w, root: `<testsuites>`},
+	}
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []junitFailure `xml:"failure"`
+	Errors    []junitFailure `xml:"error"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func (r *junitReporter) Report(pkg string, problems []scopelint.Problem) error {
+	testCase := junitTestCase{Name: pkg, ClassName: "scopelint"}
+	for _, p := range problems {
+		if p.Ignored {
+			continue
+		}
+		testCase.Failures = append(testCase.Failures, junitFailure{
+			Message: p.Text,
+			Type:    p.Category,
+			Text:    fmt.Sprintf("%v: %s (%s)", p.Position, p.Text, problemSeverity(p)),
+		})
+	}
+	return r.encode(junitTestSuite{
+		Name:      pkg,
+		Tests:     1,
+		Failures:  len(testCase.Failures),
+		TestCases: []junitTestCase{testCase},
+	})
+}
+
+// ReportError reports an error as a test suite of the package with an erroneous test case.
+func (r *junitReporter) ReportError(e lintError) error {
+	name := e.Package
+	if name == "" {
+		name = "scopelint"
+	}
+	return r.encode(junitTestSuite{
+		Name:   name,
+		Tests:  1,
+		Errors: 1,
+		TestCases: []junitTestCase{{
+			Name:      name,
+			ClassName: "scopelint",
+			Errors:    []junitFailure{{Message: e.Message, Type: e.Class, Text: e.Message}},
+		}},
+	})
+}
+
+func (r *junitReporter) Close() error {
+	return r.end("testsuites")
+}